@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// securitySchemes merges the //goge:security declarations of all packages with the
+// built-in schemes they do not redefine.
+func securitySchemes(apis map[string]*scanner.PackageAPIs) map[string]scanner.SecurityScheme {
+	out := map[string]scanner.SecurityScheme{}
+	for name, s := range scanner.BuiltinSchemes {
+		out[name] = s
+	}
+	for _, dir := range sortedDirs(apis) {
+		for _, s := range apis[dir].Security {
+			out[s.Name] = s
+		}
+	}
+	return out
+}
+
+// checkEndpointSchemes reports schemes declared twice with different settings, auth= naming an
+// unknown scheme and gogeAuth fields on endpoints without auth=.
+func checkEndpointSchemes(root string, apis map[string]*scanner.PackageAPIs) []error {
+	var errs []error
+	declared := map[string]scanner.SecurityScheme{}
+	for _, dir := range sortedDirs(apis) {
+		for _, s := range apis[dir].Security {
+			prev, ok := declared[s.Name]
+			if !ok {
+				declared[s.Name] = s
+				continue
+			}
+			same := prev
+			same.Pos = s.Pos
+			if !reflect.DeepEqual(same, s) {
+				errs = append(errs, fmt.Errorf("%s: security scheme %s is already declared differently at %s", s.Pos, s.Name, prev.Pos))
+			}
+		}
+	}
+
+	schemes := securitySchemes(apis)
+	for _, dir := range sortedDirs(apis) {
+		pkg := apis[dir]
+		for _, ep := range pkg.Endpoints {
+			if ep.Auth != "" {
+				if _, ok := schemes[ep.Auth]; !ok {
+					errs = append(errs, fmt.Errorf("%s: %s: unknown security scheme %q; declare it with //goge:security %s type=...", ep.Pos, ep.MethodName, ep.Auth, ep.Auth))
+				}
+				continue
+			}
+			if !ep.InputIsStruct {
+				continue
+			}
+			st := findStructAST(root, pkg, strings.TrimPrefix(ep.InputTypeExpr, "*"))
+			for _, b := range ExtractBindingsRecursive(pkg, st) {
+				if b.Kind == "auth" {
+					errs = append(errs, fmt.Errorf("%s: %s: field %s has %s but the endpoint has no auth=", ep.Pos, ep.MethodName, b.Name, _TAG_AUTH))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func sortedDirs(apis map[string]*scanner.PackageAPIs) []string {
+	dirs := make([]string, 0, len(apis))
+	for dir := range apis {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// credentialExpr reads the credentials of a scheme from the request.
+func credentialExpr(s scanner.SecurityScheme) string {
+	switch {
+	case s.Type == "apiKey" && s.In == "query":
+		return fmt.Sprintf("c.Query(%q)", s.Key)
+	case s.Type == "apiKey" && s.In == "cookie":
+		return fmt.Sprintf("c.Cookies(%q)", s.Key)
+	case s.Type == "apiKey":
+		return fmt.Sprintf("c.Get(%q)", s.Key)
+	case s.Type == "http" && s.Scheme == "basic":
+		return `goge.AuthToken(c.Get(fiber.HeaderAuthorization), "Basic")`
+	default:
+		// bearer, oauth2 and openIdConnect all send a bearer token
+		return `goge.AuthToken(c.Get(fiber.HeaderAuthorization), "Bearer")`
+	}
+}
+
+// buildAuthCode authenticates the request and leaves `principal` in scope.
+func buildAuthCode(s scanner.SecurityScheme, scopes []string) string {
+	scopeList := ""
+	if len(scopes) > 0 {
+		quoted := make([]string, len(scopes))
+		for i, sc := range scopes {
+			quoted[i] = fmt.Sprintf("%q", sc)
+		}
+		scopeList = fmt.Sprintf(", Scopes: []string{%s}", strings.Join(quoted, ", "))
+	}
+	return fmt.Sprintf(`principal, err := goge.Authenticate(c.UserContext(), h.auth, goge.Credentials{Scheme: %q, Type: %q, Value: %s%s})
+	if err != nil {
+		return gogeAuthError(err)
+	}
+	c.SetUserContext(goge.WithPrincipal(c.UserContext(), principal))`, s.Name, s.Type, credentialExpr(s), scopeList)
+}
+
+// buildPrincipalCode assigns the principal to gogeAuth fields after binding, so a
+// request body can never fill them.
+func buildPrincipalCode(binds []FieldBind) string {
+	var sb strings.Builder
+	for _, b := range binds {
+		if b.Kind != "auth" {
+			continue
+		}
+		if b.TypeExpr == "any" || b.TypeExpr == "interface{}" {
+			fmt.Fprintf(&sb, "\treq.%s = principal\n", b.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "\t{\n\t\tp, ok := principal.(%s)\n", b.TypeExpr)
+		fmt.Fprintf(&sb, "\t\tif !ok && principal != nil {\n\t\t\treturn fiber.NewError(fiber.StatusInternalServerError, %q)\n\t\t}\n",
+			"principal is not a "+b.TypeExpr)
+		fmt.Fprintf(&sb, "\t\treq.%s = p\n\t}\n", b.Name)
+	}
+	return sb.String()
+}
+
+// qualifiedType writes a field type so that it compiles in the service package, adding
+// the import alias to types of a DTO loaded from another package.
+func qualifiedType(pkg *scanner.PackageAPIs, owner *astStruct, expr ast.Expr) string {
+	if owner == nil || owner.ImportPath == "" {
+		return types.ExprString(expr)
+	}
+	alias := importAlias(pkg, owner.ImportPath)
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if ast.IsExported(t.Name) {
+			return alias + "." + t.Name
+		}
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + qualifiedType(pkg, owner, t.X)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + qualifiedType(pkg, owner, t.Elt)
+		}
+	case *ast.MapType:
+		return "map[" + qualifiedType(pkg, owner, t.Key) + "]" + qualifiedType(pkg, owner, t.Value)
+	}
+	return types.ExprString(expr)
+}
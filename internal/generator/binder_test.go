@@ -42,3 +42,83 @@ func TestBuildBindCode_Simple(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildBindCode_Enum(t *testing.T) {
+	status := &enumType{Name: "Status", Kind: kindString, Values: []enumValue{
+		{Name: "Active", Value: `"active"`},
+		{Name: "Inactive", Value: `"inactive"`},
+	}}
+	level := &enumType{Name: "Level", Kind: kindInt, Values: []enumValue{
+		{Name: "Low", Value: "1"},
+		{Name: "High", Value: "2"},
+	}}
+	binds := []FieldBind{
+		{Name: "Status", Kind: "query", Key: "status", QueryFunc: "Query", KindHint: kindString, TypeExpr: "Status", Enum: status},
+		{Name: "Level", Kind: "header", Key: "X-Level", KindHint: kindInt, TypeExpr: "dto.Level", Enum: level},
+		{Name: "Shade", Kind: "body", Key: "shade", KindHint: kindString, TypeExpr: "Status", Enum: status},
+		{Name: "Min", Kind: "query", Key: "min", QueryFunc: "Query", KindHint: kindInt, TypeExpr: "dto.Level", Enum: level},
+	}
+
+	code := BuildBindCode(binds)
+	for _, w := range []string{
+		`req.Status = Status(c.Query("status"))`,
+		`strconv.Atoi(raw)`,
+		`req.Level = dto.Level(v)`,
+		`if raw := c.Query("min", ""); raw != "" {`,
+		`req.Min = dto.Level(v)`,
+	} {
+		if !strings.Contains(code, w) {
+			t.Fatalf("missing line: %s\ncode:\n%s", w, code)
+		}
+	}
+	if strings.Contains(code, "req.Shade") {
+		t.Fatalf("body fields must not be bound:\n%s", code)
+	}
+	if !BindsNeedStrconv(binds) {
+		t.Fatal("int enum header needs strconv")
+	}
+
+	check := BuildEnumCheckCode(binds)
+	for _, w := range []string{
+		`case "active", "inactive":`,
+		`case 1, 2:`,
+		`if req.Shade != "" {`,
+		`"invalid X-Level: must be one of 1, 2"`,
+	} {
+		if !strings.Contains(check, w) {
+			t.Fatalf("missing line: %s\ncode:\n%s", w, check)
+		}
+	}
+}
+
+func TestBuildRespCode(t *testing.T) {
+	binds := []FieldBind{
+		{Name: "Location", Kind: "respHeader", Key: "Location", RespType: "string"},
+		{Name: "Count", Kind: "respHeader", Key: "X-Count", RespType: "int"},
+		{Name: "Next", Kind: "respHeader", Key: "X-Next", RespType: "*int64"},
+		{Name: "Version", Kind: "respHeader", Key: "ETag", RespType: "semver.Version"},
+		{Name: "Session", Kind: "respCookie", Key: "session", RespType: "string", Options: []string{"httpOnly", "maxAge=60"}},
+		{Name: "ID", Kind: "url", Key: "id"},
+	}
+	code := BuildRespCode(binds)
+	for _, w := range []string{
+		`if v := res.Location; v != "" {`,
+		`c.Set("Location", v)`,
+		`if v := res.Count; v != 0 {`,
+		`c.Set("X-Count", strconv.Itoa(v))`,
+		`if res.Next != nil {`,
+		`c.Set("X-Next", strconv.FormatInt(*res.Next, 10))`,
+		`if v := fmt.Sprint(res.Version); v != "" {`,
+		`c.Cookie(&fiber.Cookie{Name: "session", Value: v, HTTPOnly: true, MaxAge: 60})`,
+	} {
+		if !strings.Contains(code, w) {
+			t.Fatalf("missing line: %s\ncode:\n%s", w, code)
+		}
+	}
+	if strings.Contains(code, "res.ID") {
+		t.Fatalf("request bindings leaked into response code:\n%s", code)
+	}
+	if !RespNeedsFmt(binds) || !RespNeedsStrconv(binds) {
+		t.Fatal("non-string headers need fmt and strconv")
+	}
+}
@@ -3,7 +3,9 @@ package generator
 import (
 	"fmt"
 	"go/ast"
+	"go/types"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/xehrad/goge/internal/scanner"
@@ -23,16 +25,32 @@ const (
 	_TAG_QUERY  = "gogeQuery"
 	_TAG_URL    = "gogeUrl"
 	_TAG_COOKIE = "gogeCookie"
+
+	_TAG_RESP_HEADER = "gogeRespHeader"
+	_TAG_RESP_COOKIE = "gogeRespCookie"
+
+	_TAG_AUTH = "gogeAuth" // receives the principal returned by the Authenticator
+
+	_TAG_PAGE = "gogePage" // limits of an embedded goge.Page or goge.Cursor
 )
 
 type FieldBind struct {
 	Name         string
-	Kind         string // header|query|url|cookie
+	Kind         string // header|query|url|cookie|body|auth, page|cursor, respHeader|respCookie for response fields
 	Key          string
 	QueryFunc    string
 	DefaultValue string
 	HasDefault   bool
 	KindHint     valKind
+	TypeExpr     string    // named field type when a conversion is required, e.g. "Status"
+	TypeImport   string    // import path of the package TypeExpr is qualified with, if any
+	Enum         *enumType // set when the field type declares enum constants
+	RespType     string    // response fields: the field type as written, e.g. "*int64"
+	Options      []string  // extra tag options after the key, e.g. "httpOnly"
+	Doc          string    // field comment, used as the OpenAPI description
+	Example      string    // gogeExample or example tag, used as the OpenAPI example
+	Limit        int       // page and cursor: default ?limit=
+	MaxLimit     int       // page and cursor: largest ?limit= accepted
 }
 
 // ExtractBindingsRecursive handles embedded structs
@@ -52,11 +70,15 @@ func extractBindingsRecursive(pkg *scanner.PackageAPIs, st *astStruct, visited m
 		visited[key] = true
 	}
 
-	binds := ExtractBindings(pkg, st.Struct)
+	binds := ExtractBindings(pkg, st)
 	for _, f := range st.Fields() {
 		if len(f.Names) != 0 {
 			continue
 		}
+		if b, ok := pageBind(pkg, st, f); ok {
+			binds = append(binds, b)
+			continue
+		}
 		embedded := resolveEmbeddedStruct(pkg, st, f.Type)
 		if embedded == nil {
 			continue
@@ -74,11 +96,9 @@ func resolveEmbeddedStruct(pkg *scanner.PackageAPIs, owner *astStruct, expr ast.
 		return resolveEmbeddedStruct(pkg, owner, t.X)
 	case *ast.SelectorExpr:
 		if ident, ok := t.X.(*ast.Ident); ok {
-			if owner != nil && owner.ImportPath != "" {
-				// For structs loaded from an import, reuse the same loader.
-				return owner.load(t.Sel.Name)
-			}
-			if importPath, ok := pkg.Imports[ident.Name]; ok {
+			// resolved through the imports of the file declaring the owner, which
+			// may be a DTO of another package
+			if importPath, ok := owner.importOf(pkg, ident.Name); ok {
 				moduleDir := findModuleRoot(pkg.PkgDir)
 				if moduleDir == "" {
 					moduleDir = pkg.PkgDir
@@ -90,20 +110,30 @@ func resolveEmbeddedStruct(pkg *scanner.PackageAPIs, owner *astStruct, expr ast.
 	return nil
 }
 
-func ExtractBindings(pkg *scanner.PackageAPIs, st *ast.StructType) []FieldBind {
+func ExtractBindings(pkg *scanner.PackageAPIs, st *astStruct) []FieldBind {
 	binds := []FieldBind{}
-	if st == nil || st.Fields == nil {
+	if st == nil || st.Struct == nil || st.Struct.Fields == nil {
 		return binds
 	}
 
-	for _, f := range st.Fields.List {
-		if len(f.Names) == 0 || f.Tag == nil {
+	for _, f := range st.Struct.Fields.List {
+		if len(f.Names) == 0 {
 			continue
 		}
 		name := f.Names[0].Name
+		enum, typeExpr, typeImport := findEnum(pkg, st, f.Type)
+		if f.Tag == nil {
+			if enum != nil && ast.IsExported(name) {
+				binds = append(binds, FieldBind{Name: name, Kind: "body", Key: name, KindHint: enum.Kind, TypeExpr: typeExpr, TypeImport: typeImport, Enum: enum})
+			}
+			continue
+		}
 		stag := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
 
 		addBind := func(kind, key, def, qfunc string, vk valKind) {
+			if enum != nil {
+				vk = enum.Kind
+			}
 			binds = append(binds, FieldBind{
 				Name:         name,
 				Kind:         kind,
@@ -112,33 +142,81 @@ func ExtractBindings(pkg *scanner.PackageAPIs, st *ast.StructType) []FieldBind {
 				DefaultValue: def,
 				HasDefault:   def != "",
 				KindHint:     vk,
+				TypeExpr:     typeExpr,
+				TypeImport:   typeImport,
+				Enum:         enum,
+				Doc:          fieldDoc(f),
+				Example:      tagExample(f),
 			})
 		}
 
+		bound := false
 		if v, ok := stag.Lookup(_TAG_HEADER); ok {
 			key, def := parseBindingKey(v)
 			_, vk := fiberQueryMethodAndKind(f.Type)
 			addBind("header", key, def, "", vk)
+			bound = true
 		}
 		if v, ok := stag.Lookup(_TAG_QUERY); ok {
 			key, def := parseBindingKey(v)
 			method, vk := fiberQueryMethodAndKind(f.Type)
 			addBind("query", key, def, method, vk)
+			bound = true
 		}
 		if v, ok := stag.Lookup(_TAG_URL); ok {
 			key, _ := parseBindingKey(v)
 			_, vk := fiberQueryMethodAndKind(f.Type)
 			addBind("url", key, "", "", vk)
+			bound = true
 		}
 		if v, ok := stag.Lookup(_TAG_COOKIE); ok {
 			key, def := parseBindingKey(v)
 			_, vk := fiberQueryMethodAndKind(f.Type)
 			addBind("cookie", key, def, "", vk)
+			bound = true
+		}
+
+		if _, ok := stag.Lookup(_TAG_AUTH); ok {
+			binds = append(binds, FieldBind{Name: name, Kind: "auth", TypeExpr: qualifiedType(pkg, st, f.Type), TypeImport: st.ImportPath})
+			bound = true
+		}
+
+		if v, ok := stag.Lookup(_TAG_RESP_HEADER); ok {
+			key, _ := parseBindingKey(v)
+			binds = append(binds, FieldBind{Name: name, Kind: "respHeader", Key: key, RespType: types.ExprString(f.Type), Enum: enum, Doc: fieldDoc(f)})
+			bound = true
+		}
+		if v, ok := stag.Lookup(_TAG_RESP_COOKIE); ok {
+			parts := strings.Split(v, ",")
+			binds = append(binds, FieldBind{Name: name, Kind: "respCookie", Key: strings.TrimSpace(parts[0]), RespType: types.ExprString(f.Type), Enum: enum, Options: parts[1:], Doc: fieldDoc(f)})
+			bound = true
+		}
+
+		// enum fields filled by the body parser are validated too
+		if !bound && enum != nil {
+			key := name
+			if v, ok := stag.Lookup("json"); ok {
+				if n := strings.Split(v, ",")[0]; n == "-" {
+					continue
+				} else if n != "" {
+					key = n
+				}
+			}
+			binds = append(binds, FieldBind{Name: name, Kind: "body", Key: key, KindHint: enum.Kind, TypeExpr: typeExpr, TypeImport: typeImport, Enum: enum})
 		}
 	}
 	return binds
 }
 
+// fieldDoc returns the comment above a field, or the one after it on the same line.
+func fieldDoc(f *ast.Field) string {
+	doc := f.Doc.Text()
+	if doc == "" {
+		doc = f.Comment.Text()
+	}
+	return strings.Join(strings.Fields(doc), " ")
+}
+
 func parseBindingKey(v string) (key string, def string) {
 	parts := strings.Split(v, ",")
 	key = strings.TrimSpace(parts[0])
@@ -193,31 +271,207 @@ func defaultLiteral(v string, k valKind) string {
 func BuildBindCode(binds []FieldBind) string {
 	var sb strings.Builder
 	for _, b := range binds {
+		if b.Kind == "page" || b.Kind == "cursor" {
+			fmt.Fprintf(&sb, "\tif err := req.%s.Bind(c.Query(%q), c.Query(\"limit\"), %d, %d); err != nil {\n", b.Name, b.Kind, b.Limit, b.MaxLimit)
+			fmt.Fprintf(&sb, "\t\treturn fiber.NewError(fiber.StatusBadRequest, err.Error())\n\t}\n")
+			continue
+		}
+		if b.TypeExpr != "" && b.KindHint == kindInt && b.Kind != "body" {
+			// integer enums are parsed strictly: c.QueryInt would read "abc" as 0, "not provided"
+			fmt.Fprintf(&sb, "\tif raw := %s; raw != \"\" {\n", rawGetter(b))
+			fmt.Fprintf(&sb, "\t\tv, err := strconv.Atoi(raw)\n\t\tif err != nil {\n\t\t\treturn fiber.ErrBadRequest\n\t\t}\n")
+			fmt.Fprintf(&sb, "\t\treq.%s = %s(v)\n\t}\n", b.Name, b.TypeExpr)
+			continue
+		}
+		var expr string
 		switch b.Kind {
 		case "header":
 			if b.HasDefault {
-				fmt.Fprintf(&sb, "\treq.%s = c.Get(%q, %s)\n",
-					b.Name, b.Key, defaultLiteral(b.DefaultValue, kindString))
+				expr = fmt.Sprintf("c.Get(%q, %s)", b.Key, defaultLiteral(b.DefaultValue, kindString))
 			} else {
-				fmt.Fprintf(&sb, "\treq.%s = c.Get(%q)\n", b.Name, b.Key)
+				expr = fmt.Sprintf("c.Get(%q)", b.Key)
 			}
 		case "query":
 			if b.HasDefault {
-				fmt.Fprintf(&sb, "\treq.%s = c.%s(%q, %s)\n",
-					b.Name, b.QueryFunc, b.Key, defaultLiteral(b.DefaultValue, b.KindHint))
+				expr = fmt.Sprintf("c.%s(%q, %s)", b.QueryFunc, b.Key, defaultLiteral(b.DefaultValue, b.KindHint))
 			} else {
-				fmt.Fprintf(&sb, "\treq.%s = c.%s(%q)\n", b.Name, b.QueryFunc, b.Key)
+				expr = fmt.Sprintf("c.%s(%q)", b.QueryFunc, b.Key)
 			}
 		case "url":
-			fmt.Fprintf(&sb, "\treq.%s = c.Params(%q)\n", b.Name, b.Key)
+			expr = fmt.Sprintf("c.Params(%q)", b.Key)
 		case "cookie":
 			if b.HasDefault {
-				fmt.Fprintf(&sb, "\treq.%s = c.Cookies(%q, %s)\n",
-					b.Name, b.Key, defaultLiteral(b.DefaultValue, kindString))
+				expr = fmt.Sprintf("c.Cookies(%q, %s)", b.Key, defaultLiteral(b.DefaultValue, kindString))
 			} else {
-				fmt.Fprintf(&sb, "\treq.%s = c.Cookies(%q)\n", b.Name, b.Key)
+				expr = fmt.Sprintf("c.Cookies(%q)", b.Key)
 			}
+		default:
+			continue
+		}
+		if b.TypeExpr != "" {
+			expr = fmt.Sprintf("%s(%s)", b.TypeExpr, expr)
 		}
+		fmt.Fprintf(&sb, "\treq.%s = %s\n", b.Name, expr)
 	}
 	return sb.String()
 }
+
+func rawGetter(b FieldBind) string {
+	switch b.Kind {
+	case "header":
+		return fmt.Sprintf("c.Get(%q, %s)", b.Key, defaultLiteral(b.DefaultValue, kindString))
+	case "cookie":
+		return fmt.Sprintf("c.Cookies(%q, %s)", b.Key, defaultLiteral(b.DefaultValue, kindString))
+	case "query":
+		return fmt.Sprintf("c.Query(%q, %s)", b.Key, defaultLiteral(b.DefaultValue, kindString))
+	default:
+		return fmt.Sprintf("c.Params(%q)", b.Key)
+	}
+}
+
+// BindsNeedStrconv reports whether BuildBindCode output references strconv.
+func BindsNeedStrconv(binds []FieldBind) bool {
+	for _, b := range binds {
+		if b.TypeExpr != "" && b.KindHint == kindInt && b.Kind != "body" {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildEnumCheckCode rejects values outside the declared constants of enum fields.
+// The zero value is accepted as "not provided".
+func BuildEnumCheckCode(binds []FieldBind) string {
+	var sb strings.Builder
+	for _, b := range binds {
+		if b.Enum == nil || len(b.Enum.Values) == 0 {
+			continue
+		}
+		lits := b.Enum.Literals()
+		allowed := make([]string, len(lits))
+		for i, l := range lits {
+			allowed[i] = strings.Trim(l, `"`)
+		}
+		fmt.Fprintf(&sb, "\tif req.%s != %s {\n", b.Name, b.Enum.ZeroLiteral())
+		fmt.Fprintf(&sb, "\t\tswitch req.%s {\n\t\tcase %s:\n\t\tdefault:\n", b.Name, strings.Join(lits, ", "))
+		fmt.Fprintf(&sb, "\t\t\treturn fiber.NewError(fiber.StatusBadRequest, %q)\n",
+			fmt.Sprintf("invalid %s: must be one of %s", b.Key, strings.Join(allowed, ", ")))
+		fmt.Fprintf(&sb, "\t\t}\n\t}\n")
+	}
+	return sb.String()
+}
+
+// BuildRespCode copies response fields tagged gogeRespHeader/gogeRespCookie onto the reply.
+// Zero values are skipped so services can opt out per call; pointers are skipped when nil
+// and sent otherwise, zero included.
+func BuildRespCode(binds []FieldBind) string {
+	var sb strings.Builder
+	for _, b := range binds {
+		var set string
+		switch b.Kind {
+		case "respHeader":
+			set = fmt.Sprintf("c.Set(%q, %%s)", b.Key)
+		case "respCookie":
+			set = fmt.Sprintf("c.Cookie(&fiber.Cookie{Name: %q, Value: %%s%s})", b.Key, cookieOptions(b.Options))
+		default:
+			continue
+		}
+		text, zero, ptr := respFormat(b)
+		switch {
+		case ptr:
+			fmt.Fprintf(&sb, "\tif res.%s != nil {\n\t\t%s\n\t}\n", b.Name, fmt.Sprintf(set, text("*res."+b.Name)))
+		case zero == "":
+			// no comparable zero value: skip the empty text
+			fmt.Fprintf(&sb, "\tif v := %s; v != \"\" {\n\t\t%s\n\t}\n", text("res."+b.Name), fmt.Sprintf(set, "v"))
+		default:
+			fmt.Fprintf(&sb, "\tif v := res.%s; v != %s {\n\t\t%s\n\t}\n", b.Name, zero, fmt.Sprintf(set, text("v")))
+		}
+	}
+	return sb.String()
+}
+
+// respFormat tells how BuildRespCode writes the response field b: text turns a value of
+// its type into the header value, zero is the value it is skipped at, "" when the type
+// has none to compare with, and ptr reports a pointer, whose element text formats.
+func respFormat(b FieldBind) (text func(v string) string, zero string, ptr bool) {
+	typ, ptr := strings.CutPrefix(b.RespType, "*")
+	kind := typ
+	if b.Enum != nil {
+		kind = "int64"
+		if b.Enum.Kind == kindString {
+			kind = "string"
+		}
+	}
+	switch kind {
+	case "string":
+		if typ == "string" {
+			return func(v string) string { return v }, `""`, ptr
+		}
+		return func(v string) string { return "string(" + v + ")" }, `""`, ptr
+	case "int":
+		return func(v string) string { return "strconv.Itoa(" + v + ")" }, "0", ptr
+	case "int64":
+		if typ == "int64" {
+			return func(v string) string { return "strconv.FormatInt(" + v + ", 10)" }, "0", ptr
+		}
+		return func(v string) string { return "strconv.FormatInt(int64(" + v + "), 10)" }, "0", ptr
+	case "int8", "int16", "int32":
+		return func(v string) string { return "strconv.FormatInt(int64(" + v + "), 10)" }, "0", ptr
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return func(v string) string { return "strconv.FormatUint(uint64(" + v + "), 10)" }, "0", ptr
+	case "float32":
+		return func(v string) string { return "strconv.FormatFloat(float64(" + v + "), 'g', -1, 32)" }, "0", ptr
+	case "float64":
+		return func(v string) string { return "strconv.FormatFloat(" + v + ", 'g', -1, 64)" }, "0", ptr
+	case "bool":
+		return func(v string) string { return "strconv.FormatBool(" + v + ")" }, "false", ptr
+	default:
+		return func(v string) string { return "fmt.Sprint(" + v + ")" }, "", ptr
+	}
+}
+
+// cookieOptions maps `httpOnly`, `secure`, `path=/`, `domain=x`, `maxAge=3600`, `sameSite=Lax`.
+func cookieOptions(opts []string) string {
+	var sb strings.Builder
+	for _, o := range opts {
+		key, val, _ := strings.Cut(strings.TrimSpace(o), "=")
+		switch key {
+		case "httpOnly":
+			sb.WriteString(", HTTPOnly: true")
+		case "secure":
+			sb.WriteString(", Secure: true")
+		case "path":
+			fmt.Fprintf(&sb, ", Path: %q", val)
+		case "domain":
+			fmt.Fprintf(&sb, ", Domain: %q", val)
+		case "maxAge":
+			if n, err := strconv.Atoi(val); err == nil {
+				fmt.Fprintf(&sb, ", MaxAge: %d", n)
+			}
+		case "sameSite":
+			fmt.Fprintf(&sb, ", SameSite: %q", val)
+		}
+	}
+	return sb.String()
+}
+
+// RespNeedsFmt reports whether BuildRespCode output references fmt.
+func RespNeedsFmt(binds []FieldBind) bool {
+	return respUses(binds, "fmt.")
+}
+
+// RespNeedsStrconv reports whether BuildRespCode output references strconv.
+func RespNeedsStrconv(binds []FieldBind) bool {
+	return respUses(binds, "strconv.")
+}
+
+func respUses(binds []FieldBind, pkg string) bool {
+	for _, b := range binds {
+		if b.Kind == "respHeader" || b.Kind == "respCookie" {
+			if text, _, _ := respFormat(b); strings.HasPrefix(text("v"), pkg) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// cacheControl is the Cache-Control header of an endpoint annotated cache= or etag:
+// clients keep the result for cache= and revalidate it with its ETag otherwise.
+// Authenticated results are private to the client.
+func cacheControl(ep scanner.Endpoint) string {
+	if ep.CacheMaxAge == 0 {
+		return "no-cache"
+	}
+	cc := fmt.Sprintf("max-age=%d", int(ep.CacheMaxAge/time.Second))
+	if ep.Auth != "" {
+		cc = "private, " + cc
+	}
+	return cc
+}
+
+// storesResponses tells whether the handler of ep keeps its replies in the goge response
+// cache. Authenticated endpoints do not: the key holds no principal.
+func storesResponses(ep scanner.Endpoint) bool {
+	return ep.CacheMaxAge > 0 && ep.Auth == ""
+}
+
+// buildCacheKey lists the arguments of goge.CacheKey for ev: the route, then the
+// negotiated media type and every value bound from the request.
+func buildCacheKey(ep scanner.Endpoint, ev endpointVM) string {
+	args := []string{fmt.Sprintf("%q", strings.ToUpper(ep.HTTPMethod)+" "+ep.Path)}
+	if ev.MediaTypes != "" {
+		args = append(args, "mediaType")
+	}
+	if !ep.InputIsStruct {
+		if ev.CallArg != "" {
+			args = append(args, ev.CallArg)
+		}
+		return strings.Join(args, ", ")
+	}
+	for _, b := range ev.Binds {
+		switch b.Kind {
+		case "url", "query", "header", "cookie", "page", "cursor":
+			args = append(args, "req."+b.Name)
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// cacheTTL writes the duration of cache= as a Go expression.
+func cacheTTL(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%d * time.Minute", d/time.Minute)
+	}
+	return fmt.Sprintf("%d * time.Second", d/time.Second)
+}
+
+// cacheVary lists the request headers the reply of ev depends on, for the Vary header:
+// Accept for negotiated media types and the headers bound by the input.
+func cacheVary(ev endpointVM) string {
+	var vary []string
+	if ev.MediaTypes != "" {
+		vary = append(vary, fmt.Sprintf("%q", "Accept"))
+	}
+	for _, b := range ev.Binds {
+		if b.Kind == "header" {
+			vary = append(vary, fmt.Sprintf("%q", b.Key))
+		}
+	}
+	return strings.Join(vary, ", ")
+}
+
+// cacheHeaders lists the arguments of goge.ReplyHeader for ev: the headers its handler
+// sets, then the names of the cookies of resp, the response fields of its result.
+func cacheHeaders(ev endpointVM, resp []FieldBind) string {
+	keys := []string{fmt.Sprintf("%q", "Link")}
+	if ev.CacheVary != "" {
+		keys = append(keys, fmt.Sprintf("%q", "Vary"))
+	}
+	if ev.DeprecationCode != "" {
+		keys = append(keys, fmt.Sprintf("%q", "Deprecation"), fmt.Sprintf("%q", "Sunset"))
+	}
+	var cookies []string
+	for _, b := range resp {
+		switch b.Kind {
+		case "respHeader":
+			keys = append(keys, fmt.Sprintf("%q", b.Key))
+		case "respCookie":
+			cookies = append(cookies, fmt.Sprintf("%q", b.Key))
+		}
+	}
+	return strings.Join(append([]string{"[]string{" + strings.Join(keys, ", ") + "}"}, cookies...), ", ")
+}
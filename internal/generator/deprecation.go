@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// buildDeprecationCode announces the deprecation of ep on every reply: the Deprecation
+// header holds the date of deprecated= (RFC 9745 only defines a date, so the bare flag
+// sends none), Sunset the date the endpoint goes away (RFC 8594), and Link the
+// migration guide and the path of the next version, if any. The call is then reported
+// to the goge.OnDeprecated hook.
+func buildDeprecationCode(ep scanner.Endpoint, successor string) string {
+	var sb strings.Builder
+	if ep.DeprecatedAt != "" {
+		fmt.Fprintf(&sb, "c.Set(%q, %q)\n", "Deprecation", fmt.Sprintf("@%d", parseDate(ep.DeprecatedAt).Unix()))
+	}
+	if ep.Sunset != "" {
+		fmt.Fprintf(&sb, "c.Set(%q, %q)\n", "Sunset", parseDate(ep.Sunset).Format(http.TimeFormat))
+	}
+	if ep.DeprecationURL != "" {
+		fmt.Fprintf(&sb, "c.Append(fiber.HeaderLink, %q)\n", "<"+ep.DeprecationURL+`>; rel="deprecation"; type="text/html"`)
+	}
+	if successor != "" {
+		fmt.Fprintf(&sb, "if p := goge.SuccessorPath(c.Path(), c.Route().Path, %q, %q, %q); p != \"\" {\n", ep.BasePath, ep.Version, successor)
+		fmt.Fprintf(&sb, "\tc.Append(fiber.HeaderLink, \"<\"+p+%q)\n}\n", `>; rel="successor-version"`)
+	}
+	fmt.Fprintf(&sb, "goge.ReportDeprecated(c.UserContext(), goge.Deprecation{Method: %q, Path: %q, Operation: %q",
+		strings.ToUpper(ep.HTTPMethod), ep.Path, ep.MethodName)
+	if ep.Sunset != "" {
+		fmt.Fprintf(&sb, ", Sunset: %q", ep.Sunset)
+	}
+	sb.WriteString("})")
+	return sb.String()
+}
+
+// parseDate reads the dates of deprecated= and sunset=, checked by the scanner, as
+// midnight UTC.
+func parseDate(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
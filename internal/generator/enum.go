@@ -0,0 +1,214 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+type enumValue struct {
+	Name  string // constant name as declared
+	Value string // Go literal, e.g. "active" (quoted) or 2
+}
+
+// enumType is a named string/int type with a set of declared constants.
+type enumType struct {
+	Name   string
+	Kind   valKind // kindString or kindInt
+	Values []enumValue
+}
+
+// Literals returns the distinct constant literals in declaration order.
+func (e *enumType) Literals() []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(e.Values))
+	for _, v := range e.Values {
+		if seen[v.Value] {
+			continue
+		}
+		seen[v.Value] = true
+		out = append(out, v.Value)
+	}
+	return out
+}
+
+// ZeroLiteral is the zero value of the underlying type, treated as "not provided".
+func (e *enumType) ZeroLiteral() string {
+	if e.Kind == kindInt {
+		return "0"
+	}
+	return `""`
+}
+
+var enumCache = struct {
+	sync.Mutex
+	enums map[string]map[string]*enumType // pkgDir or import path => type name => enum
+}{
+	enums: map[string]map[string]*enumType{},
+}
+
+// nopImporter lets go/types evaluate constants without resolving imports;
+// enum declarations rarely depend on other packages.
+type nopImporter struct{}
+
+func (nopImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("import %s not resolved", path)
+}
+
+// collectEnums type-checks the given files (ignoring errors) and returns every named
+// string or integer type that has at least one constant of that type declared.
+func collectEnums(fset *token.FileSet, files []*ast.File) map[string]*enumType {
+	out := map[string]*enumType{}
+	if len(files) == 0 {
+		return out
+	}
+	conf := types.Config{Importer: nopImporter{}, Error: func(error) {}}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	if pkg == nil {
+		return out
+	}
+
+	scope := pkg.Scope()
+	consts := []*types.Const{}
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	for _, c := range consts {
+		named, ok := c.Type().(*types.Named)
+		if !ok || named.Obj().Pkg() != pkg {
+			continue
+		}
+		basic, ok := named.Underlying().(*types.Basic)
+		if !ok {
+			continue
+		}
+		var kind valKind
+		switch {
+		case basic.Info()&types.IsString != 0:
+			kind = kindString
+		case basic.Info()&types.IsInteger != 0:
+			kind = kindInt
+		default:
+			continue
+		}
+		typeName := named.Obj().Name()
+		e := out[typeName]
+		if e == nil {
+			e = &enumType{Name: typeName, Kind: kind}
+			out[typeName] = e
+		}
+		val := c.Val().ExactString()
+		if c.Val().Kind() == constant.String {
+			val = fmt.Sprintf("%q", constant.StringVal(c.Val()))
+		}
+		e.Values = append(e.Values, enumValue{Name: c.Name(), Value: val})
+	}
+	return out
+}
+
+func parseEnumAST(pkgDir, typeName string) *enumType {
+	enumCache.Lock()
+	defer enumCache.Unlock()
+
+	if enums, ok := enumCache.enums[pkgDir]; ok {
+		return enums[typeName]
+	}
+
+	fset := token.NewFileSet()
+	entries, _ := os.ReadDir(pkgDir)
+	files := []*ast.File{}
+	for _, f := range entries {
+		if !strings.HasSuffix(f.Name(), ".go") || strings.HasSuffix(f.Name(), "_test.go") {
+			continue
+		}
+		node, err := parser.ParseFile(fset, filepath.Join(pkgDir, f.Name()), nil, 0)
+		if err != nil {
+			continue
+		}
+		files = append(files, node)
+	}
+	enums := collectEnums(fset, files)
+	enumCache.enums[pkgDir] = enums
+	return enums[typeName]
+}
+
+func loadEnumFromImport(importPath, typeName, moduleDir string) *enumType {
+	indexImport(importPath, moduleDir)
+
+	enumCache.Lock()
+	defer enumCache.Unlock()
+	return enumCache.enums[importPath][typeName]
+}
+
+// findEnum resolves the enum behind a DTO field type. It returns the enum together with the
+// type expression that must be used to refer to it from the generated package and, when
+// the enum is declared by another package, that package's import path.
+func findEnum(pkg *scanner.PackageAPIs, owner *astStruct, expr ast.Expr) (*enumType, string, string) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if owner == nil || types.Universe.Lookup(t.Name) != nil {
+			return nil, "", ""
+		}
+		if owner.ImportPath != "" {
+			alias := importAlias(pkg, owner.ImportPath)
+			if e := loadEnumFromImport(owner.ImportPath, t.Name, owner.moduleDir); e != nil {
+				return e, alias + "." + t.Name, owner.ImportPath
+			}
+			return nil, "", ""
+		}
+		dir := owner.pkgDir
+		if dir == "" {
+			dir = pkg.PkgDir
+		}
+		if e := parseEnumAST(dir, t.Name); e != nil {
+			return e, t.Name, ""
+		}
+	case *ast.SelectorExpr:
+		ident, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil, "", ""
+		}
+		importPath, ok := owner.importOf(pkg, ident.Name)
+		if !ok {
+			return nil, "", ""
+		}
+		moduleDir := findModuleRoot(pkg.PkgDir)
+		if moduleDir == "" {
+			moduleDir = pkg.PkgDir
+		}
+		if e := loadEnumFromImport(importPath, t.Sel.Name, moduleDir); e != nil {
+			return e, importAlias(pkg, importPath) + "." + t.Sel.Name, importPath
+		}
+	}
+	return nil, "", ""
+}
+
+// importAlias returns the alias under which importPath is imported by the package, the
+// first in order when there are several, or the package's default name when the package
+// does not import it, e.g. the package of a DTO embedded by an imported DTO.
+func importAlias(pkg *scanner.PackageAPIs, importPath string) string {
+	alias := ""
+	for a, ip := range pkg.Imports {
+		if ip == importPath && (alias == "" || a < alias) {
+			alias = a
+		}
+	}
+	if alias == "" {
+		return scanner.ImportName(importPath)
+	}
+	return alias
+}
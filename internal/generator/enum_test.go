@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestCollectEnums(t *testing.T) {
+	src := `package p
+
+type Status string
+
+const (
+	Active   Status = "active"
+	Inactive Status = "inactive"
+	Default         = Active
+)
+
+type Level int
+
+const (
+	Low Level = iota + 1
+	High
+)
+
+type Plain string
+
+const untyped = "x"
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enums := collectEnums(fset, []*ast.File{f})
+
+	if _, ok := enums["Plain"]; ok {
+		t.Fatal("type without constants is not an enum")
+	}
+	st := enums["Status"]
+	if st == nil || st.Kind != kindString {
+		t.Fatalf("Status: %+v", st)
+	}
+	if got := st.Literals(); len(got) != 2 || got[0] != `"active"` || got[1] != `"inactive"` {
+		t.Fatalf("Status literals: %v", got)
+	}
+	lv := enums["Level"]
+	if lv == nil || lv.Kind != kindInt {
+		t.Fatalf("Level: %+v", lv)
+	}
+	if got := lv.Literals(); len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Fatalf("Level literals: %v", got)
+	}
+}
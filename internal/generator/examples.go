@@ -0,0 +1,432 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// _TAG_EXAMPLE documents a field with an example value; the plain `example` tag is
+// understood too.
+const _TAG_EXAMPLE = "gogeExample"
+
+// bodyExample is the value of an //goge:example function.
+type bodyExample struct {
+	fn    string
+	pos   token.Position
+	value any            // the JSON body
+	bound map[string]any // values of fields bound by goge tags, by Go field name
+}
+
+// packageExamples evaluates the //goge:example functions of pkg, keyed by exampleKey.
+func packageExamples(pkg *scanner.PackageAPIs) (map[string]*bodyExample, []error) {
+	out := map[string]*bodyExample{}
+	var errs []error
+	id := packageID(pkg.PkgDir)
+	ti := loadTypes(pkg.PkgDir)
+	for _, ex := range pkg.Examples {
+		typ, err := parser.ParseExpr(ex.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", ex.Pos, ex.Func, err))
+			continue
+		}
+		val, err := parser.ParseExpr(ex.Value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", ex.Pos, ex.Func, err))
+			continue
+		}
+		x := &exampleEval{pkg: pkg, ti: ti, file: ex.Pos.Filename, imports: ex.Imports, bound: map[string]any{}}
+		v, err := x.value(nil, typ, val, true)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", ex.Pos, ex.Func, err))
+			continue
+		}
+		key := exampleKey(id, ex.Imports, ex.Type)
+		if prev := out[key]; prev != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: duplicate example for %s, already given by %s at %s", ex.Pos, ex.Func, ex.Type, prev.fn, prev.pos))
+			continue
+		}
+		out[key] = &bodyExample{fn: ex.Func, pos: ex.Pos, value: v, bound: x.bound}
+	}
+	return out, errs
+}
+
+// packageID is the import path of the package in dir, or dir outside a module.
+func packageID(dir string) string {
+	if ip, err := importPath(dir); err == nil {
+		return ip
+	}
+	return dir
+}
+
+// exampleKey spells a type by the packages declaring its named types, so that examples
+// match the endpoint types whatever file or alias refers to them, e.g.
+// "[]example.com/app/dto.Item". A leading * is dropped.
+func exampleKey(id string, imports map[string]string, typeExpr string) string {
+	e, err := parser.ParseExpr(strings.TrimPrefix(typeExpr, "*"))
+	if err != nil {
+		return typeExpr
+	}
+	var key func(e ast.Expr) string
+	key = func(e ast.Expr) string {
+		switch t := e.(type) {
+		case *ast.Ident:
+			if predeclared[t.Name] {
+				return t.Name
+			}
+			return id + "." + t.Name
+		case *ast.SelectorExpr:
+			if x, ok := t.X.(*ast.Ident); ok {
+				if ip, ok := imports[x.Name]; ok {
+					return ip + "." + t.Sel.Name
+				}
+			}
+		case *ast.StarExpr:
+			return "*" + key(t.X)
+		case *ast.ArrayType:
+			if t.Len == nil {
+				return "[]" + key(t.Elt)
+			}
+		case *ast.MapType:
+			return "map[" + key(t.Key) + "]" + key(t.Value)
+		}
+		return types.ExprString(e)
+	}
+	return key(e)
+}
+
+// exampleEval reads the literal an //goge:example function returns as the JSON value it
+// encodes to. Composite, basic and constant literals are understood, with &, unary minus
+// and conversions around them; anything computed at run time is not.
+type exampleEval struct {
+	pkg     *scanner.PackageAPIs
+	ti      *typeInfo
+	file    string            // file declaring the function
+	imports map[string]string // of that file
+	bound   map[string]any
+}
+
+// value evaluates e of type typ. Types are written in the example's file when owner is
+// nil and in the file declaring owner otherwise. Top is set for the returned value,
+// whose goge-bound fields are collected rather than encoded.
+func (x *exampleEval) value(owner *astStruct, typ, e ast.Expr, top bool) (any, error) {
+	switch v := e.(type) {
+	case *ast.ParenExpr:
+		return x.value(owner, typ, v.X, top)
+	case *ast.UnaryExpr:
+		if v.Op == token.AND {
+			return x.value(owner, deref(typ), v.X, top)
+		}
+	case *ast.CompositeLit:
+		if v.Type != nil {
+			owner, typ = nil, v.Type
+		}
+		return x.composite(owner, deref(typ), v, top)
+	case *ast.CallExpr:
+		// a conversion such as Status("active")
+		if x.isConversion(v) {
+			return x.value(nil, v.Fun, v.Args[0], top)
+		}
+		return nil, fmt.Errorf("unsupported example value %s: only literals are understood", types.ExprString(e))
+	case *ast.Ident:
+		switch v.Name {
+		case "true", "false":
+			return v.Name == "true", nil
+		case "nil":
+			return nil, nil
+		}
+	}
+	if c := x.constant(owner, typ, e); c != nil {
+		return constantJSON(c), nil
+	}
+	return nil, fmt.Errorf("unsupported example value %s: only literals are understood", types.ExprString(e))
+}
+
+// isConversion tells whether call converts its argument to a type. Without type
+// information any call of one argument but a builtin such as len is taken for one.
+func (x *exampleEval) isConversion(call *ast.CallExpr) bool {
+	if len(call.Args) != 1 || call.Ellipsis != token.NoPos {
+		return false
+	}
+	if x.ti != nil {
+		return x.ti.eval(x.file, types.ExprString(call.Fun)) != nil
+	}
+	if id, ok := call.Fun.(*ast.Ident); ok {
+		_, builtin := types.Universe.Lookup(id.Name).(*types.Builtin)
+		return !builtin
+	}
+	return true
+}
+
+func deref(typ ast.Expr) ast.Expr {
+	if s, ok := typ.(*ast.StarExpr); ok {
+		return s.X
+	}
+	return typ
+}
+
+// constant evaluates basic literals and constants, the latter through type information
+// or, without it, among the values of the enum typ.
+func (x *exampleEval) constant(owner *astStruct, typ, e ast.Expr) constant.Value {
+	switch v := e.(type) {
+	case *ast.BasicLit:
+		if c := constant.MakeFromLiteral(v.Value, v.Kind, 0); c.Kind() != constant.Unknown {
+			return c
+		}
+	case *ast.UnaryExpr:
+		if c := x.constant(owner, typ, v.X); c != nil && (v.Op == token.SUB || v.Op == token.ADD) {
+			return constant.UnaryOp(v.Op, c, 0)
+		}
+	case *ast.Ident, *ast.SelectorExpr:
+		if c := x.ti.constant(x.file, types.ExprString(e)); c != nil {
+			return c
+		}
+		name := ""
+		if id, ok := e.(*ast.Ident); ok {
+			name = id.Name
+		} else {
+			name = e.(*ast.SelectorExpr).Sel.Name
+		}
+		if typ == nil {
+			return nil
+		}
+		enum, _, _ := findEnum(x.pkg, x.enumOwner(owner), typ)
+		if enum == nil {
+			return nil
+		}
+		for _, ev := range enum.Values {
+			if ev.Name == name {
+				lit := &ast.BasicLit{Kind: token.INT, Value: ev.Value}
+				if strings.HasPrefix(ev.Value, `"`) {
+					lit.Kind = token.STRING
+				}
+				return x.constant(owner, nil, lit)
+			}
+		}
+	}
+	return nil
+}
+
+// enumOwner stands in for the example's file when resolving enums of types written there.
+func (x *exampleEval) enumOwner(owner *astStruct) *astStruct {
+	if owner != nil {
+		return owner
+	}
+	return &astStruct{pkgDir: x.pkg.PkgDir, imports: x.imports}
+}
+
+func constantJSON(c constant.Value) any {
+	switch c.Kind() {
+	case constant.String:
+		return constant.StringVal(c)
+	case constant.Bool:
+		return constant.BoolVal(c)
+	case constant.Int:
+		if i, ok := constant.Int64Val(c); ok {
+			return i
+		}
+		return json.Number(c.ExactString())
+	case constant.Float:
+		f, _ := constant.Float64Val(c)
+		return f
+	}
+	return nil
+}
+
+func (x *exampleEval) composite(owner *astStruct, typ ast.Expr, lit *ast.CompositeLit, top bool) (any, error) {
+	switch t := typ.(type) {
+	case *ast.ArrayType:
+		out := []any{}
+		for _, el := range lit.Elts {
+			v, err := x.value(owner, t.Elt, el, false)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case *ast.MapType:
+		out := map[string]any{}
+		for _, el := range lit.Elts {
+			kv, ok := el.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("map literal without keys")
+			}
+			k, err := x.value(owner, t.Key, kv.Key, false)
+			if err != nil {
+				return nil, err
+			}
+			v, err := x.value(owner, t.Value, kv.Value, false)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(k)] = v
+		}
+		return out, nil
+	}
+	st := x.structOf(owner, typ)
+	if st == nil {
+		return nil, fmt.Errorf("unsupported example literal of type %s: use a struct, slice or map literal", types.ExprString(typ))
+	}
+	return x.object(st, lit, top)
+}
+
+// structOf resolves typ to a struct declaration.
+func (x *exampleEval) structOf(owner *astStruct, typ ast.Expr) *astStruct {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		if owner == nil {
+			return parseStructAST(x.pkg.PkgDir, t.Name)
+		}
+		return owner.load(t.Name)
+	case *ast.SelectorExpr:
+		if owner == nil {
+			id, ok := t.X.(*ast.Ident)
+			if !ok || x.imports[id.Name] == "" {
+				return nil
+			}
+			moduleDir := findModuleRoot(x.pkg.PkgDir)
+			if moduleDir == "" {
+				moduleDir = x.pkg.PkgDir
+			}
+			return loadStructFromImport(x.imports[id.Name], t.Sel.Name, moduleDir)
+		}
+		return resolveEmbeddedStruct(x.pkg, owner, t)
+	}
+	return nil
+}
+
+// object encodes a struct literal the way objectSchema describes it: by json name,
+// unexported and "-" fields left out, embedded structs flattened and fields bound by
+// goge tags collected apart.
+func (x *exampleEval) object(st *astStruct, lit *ast.CompositeLit, top bool) (map[string]any, error) {
+	type field struct {
+		name string
+		decl *ast.Field
+	}
+	var fields []field
+	for _, f := range st.Fields() {
+		if len(f.Names) == 0 {
+			fields = append(fields, field{embeddedName(f.Type), f})
+		}
+		for _, n := range f.Names {
+			fields = append(fields, field{n.Name, f})
+		}
+	}
+
+	out := map[string]any{}
+	for i, el := range lit.Elts {
+		var f field
+		valueExpr := el
+		if kv, ok := el.(*ast.KeyValueExpr); ok {
+			key, _ := kv.Key.(*ast.Ident)
+			if key == nil {
+				return nil, fmt.Errorf("invalid field key %s", types.ExprString(kv.Key))
+			}
+			for _, cand := range fields {
+				if cand.name == key.Name {
+					f = cand
+				}
+			}
+			if f.decl == nil {
+				return nil, fmt.Errorf("%s has no field %s", st.Name, key.Name)
+			}
+			valueExpr = kv.Value
+		} else if i < len(fields) {
+			f = fields[i]
+		} else {
+			return nil, fmt.Errorf("too many values in %s literal", st.Name)
+		}
+
+		v, err := x.value(st, f.decl.Type, valueExpr, top && len(f.decl.Names) == 0)
+		if err != nil {
+			return nil, err
+		}
+		tag := parseJSONTag(f.decl)
+		if m, ok := v.(map[string]any); ok && len(f.decl.Names) == 0 && tag.name == "" {
+			maps.Copy(out, m)
+			continue
+		}
+		if !ast.IsExported(f.name) {
+			continue
+		}
+		if hasGogeTag(f.decl) {
+			if top {
+				x.bound[f.name] = v
+			}
+			continue
+		}
+		if tag.skip {
+			continue
+		}
+		if tag.asString {
+			switch v.(type) {
+			case int64, float64, bool, json.Number:
+				v = fmt.Sprint(v)
+			}
+		}
+		name := f.name
+		if tag.name != "" {
+			name = tag.name
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+func embeddedName(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// tagExample returns the gogeExample or example tag of a field.
+func tagExample(f *ast.Field) string {
+	if f.Tag == nil {
+		return ""
+	}
+	tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
+	if v, ok := tag.Lookup(_TAG_EXAMPLE); ok {
+		return v
+	}
+	return tag.Get("example")
+}
+
+// exampleOf converts an example tag to the JSON type of s: numbers, booleans and objects
+// are written as JSON, arrays as JSON or comma separated values.
+func exampleOf(raw string, s *schema) any {
+	if raw == "" || s == nil {
+		return nil
+	}
+	switch s.Type {
+	case "integer", "number", "boolean", "object":
+		if json.Valid([]byte(raw)) {
+			return json.RawMessage(raw)
+		}
+	case "array":
+		if strings.HasPrefix(raw, "[") && json.Valid([]byte(raw)) {
+			return json.RawMessage(raw)
+		}
+		out := []any{}
+		for _, item := range strings.Split(raw, ",") {
+			out = append(out, exampleOf(strings.TrimSpace(item), s.Items))
+		}
+		return out
+	}
+	return raw
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+func TestExampleOf(t *testing.T) {
+	for _, tc := range []struct {
+		raw  string
+		s    *schema
+		want string
+	}{
+		{"42", &schema{Type: "integer"}, `42`},
+		{"many", &schema{Type: "integer"}, `"many"`},
+		{"true", &schema{Type: "boolean"}, `true`},
+		{"a, b", &schema{Type: "array", Items: &schema{Type: "string"}}, `["a","b"]`},
+		{"1,2", &schema{Type: "array", Items: &schema{Type: "integer"}}, `[1,2]`},
+		{`["x"]`, &schema{Type: "array"}, `["x"]`},
+		{`{"k":1}`, &schema{Type: "object"}, `{"k":1}`},
+		{"007", &schema{Type: "string"}, `"007"`},
+	} {
+		got, err := json.Marshal(exampleOf(tc.raw, tc.s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("exampleOf(%q, %s) = %s, want %s", tc.raw, tc.s.Type, got, tc.want)
+		}
+	}
+}
+
+func TestPackageExamples(t *testing.T) {
+	// outside a module: constants resolve through the enum declarations
+	dir := t.TempDir()
+	src := `package svc
+
+type service struct{}
+
+//goge:api method=POST path=/alerts
+func (s *service) Raise(req *Alert) error { return nil }
+
+type Level int
+
+const (
+	Low Level = iota + 1
+	High
+)
+
+type Alert struct {
+	Level  Level             ` + "`json:\"level\"`" + `
+	Labels map[string]string ` + "`json:\"labels\"`" + `
+	Tenant string            ` + "`gogeHeader:\"X-Tenant\"`" + `
+	note   string
+}
+
+//goge:example
+func exampleAlert() *Alert {
+	return &Alert{Level: High, Labels: map[string]string{"team": "ops"}, Tenant: "acme", note: "x"}
+}
+
+//goge:example
+func exampleAlerts() []*Alert {
+	return []*Alert{{Level: Low}, nil}
+}
+
+//goge:example
+func exampleAgain() Alert {
+	return Alert{}
+}
+
+//goge:example
+func exampleLevel() Level {
+	return Level(len("x"))
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "svc.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	apis, err := scanner.Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exs, errs := packageExamples(apis[dir])
+	if len(errs) != 2 {
+		t.Fatalf("errors: %v", errs)
+	}
+	for i, want := range []string{"duplicate example for Alert, already given by exampleAlert", "unsupported example value len"} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("error %d = %v, want %q", i, errs[i], want)
+		}
+	}
+
+	alert := exs[exampleKey(dir, nil, "*Alert")]
+	if alert == nil {
+		t.Fatalf("examples: %v", exs)
+	}
+	if got, _ := json.Marshal(alert.value); string(got) != `{"labels":{"team":"ops"},"level":2}` {
+		t.Errorf("exampleAlert = %s", got)
+	}
+	if alert.bound["Tenant"] != "acme" {
+		t.Errorf("bound = %v", alert.bound)
+	}
+	if got, _ := json.Marshal(exs[exampleKey(dir, nil, "[]*Alert")].value); string(got) != `[{"level":1},null]` {
+		t.Errorf("exampleAlerts = %s", got)
+	}
+}
@@ -2,13 +2,16 @@ package generator
 
 import (
 	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
 	"go/ast"
-	"go/format"
 	"go/parser"
 	"go/token"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -21,66 +24,334 @@ import (
 
 const fileHeader = `// Code generated by goge; DO NOT EDIT.`
 
+// wsImportPath is the Fiber WebSocket middleware used by method=WS endpoints.
+const wsImportPath = "github.com/gofiber/contrib/websocket"
+
 var tpl = template.Must(template.New("handler").Parse(`
 	package {{.PkgName}}
 
 	import (
+		{{- if .UsesResponse }}
 		"gaas/pkg/response"
+		{{- end }}
 		"github.com/gofiber/fiber/v2"
 		{{- range .ExtraImports }}
-			"{{ . }}"
+			{{ . }}
 		{{- end }}
 	)
 
+	{{- range .Services }}
+	{{- $svc := . }}
+
 	type (
-		Service interface {
+		{{ .Iface }} interface {
 			{{- range .Endpoints }}
-				{{ .MethodName }}({{ .InputArg }}) ({{ .ReturnType }}, error)
+				{{ .MethodName }}({{ .Params }}) {{ .Results }}
 			{{- end }}
 		}
 
-		Handler struct {
-			service Service
+		{{ .Handler }} struct {
+			service {{ .Iface }}
+			{{- if .UsesAuth }}
+			auth    goge.Authenticator
+			{{- end }}
 		}
 	)
 
-	func NewHandler(s Service) *Handler { return &Handler{service: s} }
+	{{ if .UsesAuth -}}
+	func {{ .Ctor }}(s {{ .Iface }}, auth goge.Authenticator) *{{ .Handler }} { return &{{ .Handler }}{service: s, auth: auth} }
+	{{- else -}}
+	func {{ .Ctor }}(s {{ .Iface }}) *{{ .Handler }} { return &{{ .Handler }}{service: s} }
+	{{- end }}
 
-	func (h *Handler) RegisterRoutes(app *fiber.App) {
+	func (h *{{ .Handler }}) RegisterRoutes(app *fiber.App) {
+		{{- if .Codecs }}
+			goge.RequireCodecs({{ range $i, $mt := .Codecs }}{{ if $i }}, {{ end }}{{ printf "%q" $mt }}{{ end }})
+		{{- end }}
 		{{- range .Endpoints }}
 			app.{{ .HTTPMethod }}("{{ .Path }}", h.{{ .MethodName }})
+			{{- if .Version }}
+			app.{{ .HTTPMethod }}("{{ .BasePath }}", gogeVersion("{{ .Version }}", {{ .VersionFallback }}, h.{{ .MethodName }}))
+			{{- end }}
+		{{- end }}
+	}
+	{{- end }}
+
+	{{- if gt (len .Services) 1 }}
+
+	// RegisterServices registers the routes of every service in the package{{ if .HasFuncs }} and GogeRouter{{ end }}.
+	func RegisterServices(app *fiber.App, {{ range .Services }}{{ .Arg }} {{ .Iface }}, {{ end }}{{ if .UsesAuth }}auth goge.Authenticator{{ end }}) {
+		{{- range .Services }}
+		{{ .Ctor }}({{ .Arg }}{{ if .UsesAuth }}, auth{{ end }}).RegisterRoutes(app)
+		{{- end }}
+		{{- if .HasFuncs }}
+		GogeRouter(app)
+		{{- end }}
+	}
+	{{- end }}
+
+	{{- if .UsesAuth }}
+
+	// gogeAuthError keeps Fiber errors and maps everything else to 401, or 403 for goge.ErrForbidden.
+	func gogeAuthError(err error) error {
+		var fe *fiber.Error
+		switch {
+		case errors.As(err, &fe):
+			return err
+		case errors.Is(err, goge.ErrForbidden):
+			return fiber.ErrForbidden
+		default:
+			return fiber.ErrUnauthorized
+		}
+	}
+	{{- end }}
+
+	{{- if .UsesCache }}
+
+	// gogeSend writes the reply of an endpoint annotated cache= or etag, or 304 when the
+	// client's If-None-Match still matches its ETag.
+	func gogeSend(c *fiber.Ctx, r goge.CachedResponse, cacheControl string) error {
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		if r.ETag != "" {
+			c.Set(fiber.HeaderETag, r.ETag)
+			if goge.MatchETag(c.Get(fiber.HeaderIfNoneMatch), r.ETag) {
+				return c.SendStatus(fiber.StatusNotModified)
+			}
+		}
+		c.Set(fiber.HeaderContentType, r.ContentType)
+		return c.Status(r.Status).Send(r.Body)
+	}
+
+	// gogeReplay sends a reply from the response cache with the headers it was sent with.
+	// Cookies are added to those of the request, other headers replace them.
+	func gogeReplay(c *fiber.Ctx, r goge.CachedResponse, cacheControl string) error {
+		for k, vs := range r.Header {
+			if k != fiber.HeaderSetCookie {
+				c.Response().Header.Del(k)
+			}
+			for _, v := range vs {
+				c.Response().Header.Add(k, v)
+			}
+		}
+		return gogeSend(c, r, cacheControl)
+	}
+	{{- end }}
+
+	{{- if .UsesVersions }}
+
+	// gogeVersion serves h to the requests for version sent to the unversioned path, and
+	// to those without an Accept-Version header when it is the fallback; the others go on
+	// to the other versions.
+	func gogeVersion(version string, fallback bool, h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			if !goge.AcceptsVersion(c.Get(goge.VersionHeader), version, fallback) {
+				return c.Next()
+			}
+			return h(c)
+		}
+	}
+	{{- end }}
+
+	{{- if .HasFuncs }}
+
+	// GogeRouter registers the endpoints implemented by package-level functions.
+	func GogeRouter(app *fiber.App) {
+		{{- if .FuncCodecs }}
+			goge.RequireCodecs({{ range $i, $mt := .FuncCodecs }}{{ if $i }}, {{ end }}{{ printf "%q" $mt }}{{ end }})
+		{{- end }}
+		{{- range .Endpoints }}
+		{{- if .Func }}
+			app.{{ .HTTPMethod }}("{{ .Path }}", {{ .HandlerName }})
+			{{- if .Version }}
+			app.{{ .HTTPMethod }}("{{ .BasePath }}", gogeVersion("{{ .Version }}", {{ .VersionFallback }}, {{ .HandlerName }}))
+			{{- end }}
+		{{- end }}
 		{{- end }}
 	}
+	{{- end }}
 
 	{{- range .Endpoints }}
 
-		{{- if .ManualFunc }}
-		func (h *Handler) {{ .MethodName }}(c *fiber.Ctx) error {
-			return h.{{ .ManualFunc }}(c)
+		{{- if eq .Stream "ws" }}
+		func {{ if not .Func }}(h *{{ .HandlerType }}) {{ end }}{{ .HandlerName }}(c *fiber.Ctx) error {
+			if !websocket.IsWebSocketUpgrade(c) {
+				return fiber.ErrUpgradeRequired
+			}
+			{{- if .AuthCode }}
+			{{ .AuthCode }}
+			{{- end }}
+			{{- if .DeprecationCode }}
+			{{ .DeprecationCode }}
+			{{- end }}
+			// the connection outlives c: keep the values of the request's context
+			ctx := c.UserContext()
+			return websocket.New(func(conn *websocket.Conn) {
+				goge.ServeWS(ctx, conn, {{ .Call }}, goge.DefaultWSOptions)
+			})(c)
+		}
+		{{- else if .ManualFunc }}
+		func {{ if not .Func }}(h *{{ .HandlerType }}) {{ end }}{{ .HandlerName }}(c *fiber.Ctx) error {
+			{{- if .AuthCode }}
+			{{ .AuthCode }}
+			{{- end }}
+			{{- if .DeprecationCode }}
+			{{ .DeprecationCode }}
+			{{- end }}
+			return {{ if not .Func }}h.{{ end }}{{ .ManualFunc }}(c)
 		}
 		{{- else }}
-		func (h *Handler) {{ .MethodName }}(c *fiber.Ctx) error {
+		func {{ if not .Func }}(h *{{ .HandlerType }}) {{ end }}{{ .HandlerName }}(c *fiber.Ctx) error {
+			{{- if .AuthCode }}
+					{{ .AuthCode }}
+			{{- end }}
+			{{- if .MediaTypes }}
+					mediaType := c.Accepts(goge.Available({{ .MediaTypes }})...)
+					if mediaType == "" {
+						return fiber.ErrNotAcceptable
+					}
+			{{- end }}
+			{{- if .DeprecationCode }}
+					{{ .DeprecationCode }}
+			{{- end }}
 			{{- if .InputIsStruct }}
 					{{ .ReqAlloc }}
-					{{- if .NeedsBodyParser }}
+					{{- if and .NeedsBodyParser .MediaTypes }}
+					if len(c.Body()) > 0 {
+						if err := goge.Unmarshal(c.Get(fiber.HeaderContentType), c.Body(), req, {{ .MediaTypes }}); err != nil {
+							if errors.Is(err, goge.ErrUnsupportedMediaType) {
+								return fiber.ErrUnsupportedMediaType
+							}
+							return fiber.ErrBadRequest
+						}
+					}
+					{{- else if .NeedsBodyParser }}
 					if err := c.BodyParser(req); err != nil {
 						return fiber.ErrBadRequest
 					}
 					{{- end }}
 					{{ .BindingCode }}
-					res, err := h.service.{{ .MethodName }}({{ .CallArg }})
-			{{- else }}
+					{{- if .PrincipalCode }}
+					{{ .PrincipalCode }}
+					{{- end }}
+					{{- if .EnumCheckCode }}
+					{{ .EnumCheckCode }}
+					{{- end }}
+			{{- else if .InputTypeExpr }}
 					// Primitive input; bind from path or query
 					{{ .PrimitiveBind }}
-					res, err := h.service.{{ .MethodName }}({{ .CallArg }})
 			{{- end }}
+			{{- if .CacheKey }}
+					cacheKey := goge.CacheKey({{ .CacheKey }})
+					if cached, ok := goge.LookupResponse(cacheKey); ok {
+						return gogeReplay(c, cached, {{ printf "%q" .CacheControl }})
+					}
+			{{- end }}
+			{{- if eq .Stream "chan" }}
+					ctx, cancel := context.WithCancel(c.UserContext())
+					res, err := {{ .Call }}({{ .CallArgs }})
+					if err != nil {
+						cancel()
+						return err
+					}
+					c.Set(fiber.HeaderContentType, "text/event-stream")
+					c.Set(fiber.HeaderCacheControl, "no-cache")
+					c.Set(fiber.HeaderConnection, "keep-alive")
+					c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+						goge.StreamSSE(ctx, cancel, w, res, goge.KeepAlive)
+					})
+					return nil
+			{{- else if eq .Stream "sink" }}
+					ctx, cancel := context.WithCancel(c.UserContext())
+					c.Set(fiber.HeaderContentType, "text/event-stream")
+					c.Set(fiber.HeaderCacheControl, "no-cache")
+					c.Set(fiber.HeaderConnection, "keep-alive")
+					c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+						goge.ServeSSE(ctx, cancel, w, goge.KeepAlive, func(sink goge.EventWriter) error {
+							return {{ .Call }}({{ .CallArgs }})
+						})
+					})
+					return nil
+			{{- else if .ReturnType }}
+					res, err := {{ .Call }}({{ .CallArgs }})
 					if err != nil {
 						return err
 					}
-			{{- if .ReturnIsBytes }}
-					return c.Send(res)
+				{{- if .RespCode }}
+					{{- if .ReturnIsPtr }}
+					if res != nil {
+						{{ .RespCode }}
+					}
+					{{- else }}
+					{{ .RespCode }}
+					{{- end }}
+				{{- end }}
+				{{- if .PageCode }}
+					{{- if .ReturnIsPtr }}
+					if res != nil {
+						{{ .PageCode }}
+					}
+					{{- else }}
+					{{ .PageCode }}
+					{{- end }}
+				{{- end }}
+				{{- if .CacheControl }}
+					{{- if eq .ReturnKind "bytes" }}
+					body := res
+					{{- else }}
+					body, err := goge.Marshal({{ .CacheContentType }}, response.ResponseDataOK(res))
+					if err != nil {
+						return err
+					}
+					{{- end }}
+					{{- if .CacheVary }}
+					c.Vary({{ .CacheVary }})
+					{{- end }}
+					reply := goge.CachedResponse{Status: {{ or .Status 200 }}, ContentType: {{ .CacheContentType }}, Body: body{{ if .ETag }}, ETag: goge.ETag(body){{ end }}}
+					{{- if .CacheKey }}
+					reply.Header = goge.ReplyHeader(c.GetRespHeaders(), {{ .CacheHeaders }})
+					goge.StoreResponse(cacheKey, reply, {{ .CacheTTL }})
+					{{- end }}
+					return gogeSend(c, reply, {{ printf "%q" .CacheControl }})
+				{{- else if eq .ReturnKind "bytes" }}
+					{{- if .ContentType }}
+					c.Set(fiber.HeaderContentType, {{ printf "%q" .ContentType }})
+					{{- end }}
+					return c{{ if .Status }}.Status({{ .Status }}){{ end }}.Send(res)
+				{{- else if eq .ReturnKind "reader" }}
+					c.Set(fiber.HeaderContentType, {{ printf "%q" (or .ContentType "application/octet-stream") }})
+					return c{{ if .Status }}.Status({{ .Status }}){{ end }}.SendStream(res)
+				{{- else if eq .ReturnKind "file" }}
+					{{- if .ReturnIsPtr }}
+					if res == nil {
+						return fiber.ErrNotFound
+					}
+					{{- end }}
+					contentType := res.ContentType
+					if contentType == "" {
+						contentType = {{ printf "%q" (or .ContentType "application/octet-stream") }}
+					}
+					c.Set(fiber.HeaderContentType, contentType)
+					c.Set(fiber.HeaderContentDisposition, res.Disposition())
+					return c{{ if .Status }}.Status({{ .Status }}){{ end }}.SendStream(res.Reader, res.Length())
+				{{- else if .MediaTypes }}
+					body, err := goge.Marshal(mediaType, response.ResponseDataOK(res))
+					if err != nil {
+						return err
+					}
+					c.Set(fiber.HeaderContentType, mediaType)
+					return c{{ if .Status }}.Status({{ .Status }}){{ end }}.Send(body)
+				{{- else }}
+					return c{{ if .Status }}.Status({{ .Status }}){{ end }}.JSON(response.ResponseDataOK(res))
+				{{- end }}
 			{{- else }}
-					return c.JSON(response.ResponseDataOK(res))
+					if err := {{ .Call }}({{ .CallArgs }}); err != nil {
+						return err
+					}
+				{{- if .Status }}
+					return c.SendStatus({{ .Status }})
+				{{- else }}
+					return c.SendStatus(fiber.StatusNoContent)
+				{{- end }}
 			{{- end }}
 		}
 		{{- end }}
@@ -89,58 +360,244 @@ var tpl = template.Must(template.New("handler").Parse(`
 
 // internal view model for template
 type endpointVM struct {
-	MethodName      string
-	HTTPMethod      string
-	Path            string
-	InputArg        string
-	ReturnType      string
-	ReturnIsBytes   bool
-	InputIsStruct   bool
-	InputTypeExpr   string
-	PrimitiveBind   string
-	CallArg         string
-	ReqAlloc        string
-	BindingCode     string
-	NeedsBodyParser bool
-	ManualFunc      string
+	MethodName       string
+	HTTPMethod       string
+	Path             string
+	InputArg         string
+	HasContext       bool
+	Params           string // service method params, e.g. "ctx context.Context, req *X"
+	Results          string // service method results, e.g. "(*Y, error)" or "error"
+	CallArgs         string // arguments passed to the service from the handler
+	ReturnType       string
+	ReturnKind       string // scanner.ReturnBytes/ReturnReader/ReturnFile for raw results
+	ContentType      string // contentType= for raw results
+	ReturnIsPtr      bool
+	Status           int    // explicit success status, 0 keeps the default
+	RespCode         string // copies gogeRespHeader/gogeRespCookie fields onto the reply
+	PageCode         string // Link headers of a goge.PageResult, see buildPageLinksCode
+	CacheControl     string // Cache-Control of endpoints annotated cache= or etag, see cacheControl
+	ETag             bool   // etag: the reply carries an ETag and If-None-Match is answered with 304
+	CacheContentType string // Go expression of the Content-Type of cached replies
+	CacheKey         string // goge.CacheKey arguments when replies are kept in the response cache
+	CacheTTL         string // Go expression of cache=, e.g. 5 * time.Minute
+	CacheVary        string // quoted request headers the reply depends on, see cacheVary
+	CacheHeaders     string // goge.ReplyHeader arguments naming the headers kept with the reply
+	Stream           string // scanner.StreamChan or scanner.StreamSink for SSE endpoints
+	MediaTypes       string // quoted produces= media types, e.g. "application/json", "application/xml"
+	InputIsStruct    bool
+	InputTypeExpr    string
+	PrimitiveBind    string
+	CallArg          string
+	ReqAlloc         string
+	BindingCode      string
+	EnumCheckCode    string
+	AuthCode         string // authenticates the request, see buildAuthCode
+	DeprecationCode  string // sets the deprecation headers, see buildDeprecationCode
+	Auth             *scanner.SecurityScheme
+	PrincipalCode    string // assigns the principal to gogeAuth fields
+	NeedsBodyParser  bool
+	ManualFunc       string
+	Func             bool   // package-level function, served by GogeRouter
+	HandlerName      string // MethodName for methods, <Name>Handler for functions
+	HandlerType      string // handler struct of the method's service, see serviceVM
+	Recv             string // receiver as written, empty for functions
+	Call             string // what the handler calls: h.service.<Method> or the function
+	Version          string // version=, see scanner.Endpoint
+	BasePath         string // path without the version prefix, dispatched on Accept-Version
+	VersionFallback  bool   // oldest version of BasePath, serving requests without Accept-Version
+
+	// used by the generated tests
+	Binds        []FieldBind
+	ParamNames   []string
+	ParamTypes   []string
+	NamedResults string
 }
 
 type pkgVM struct {
 	PkgName      string
-	ExtraImports []string
+	UsesResponse bool     // some endpoint wraps its result with response.ResponseDataOK
+	UsesAuth     bool     // some endpoint has auth=; its Handler then needs an Authenticator
+	HasFuncs     bool     // some endpoint is a package-level function, so GogeRouter is generated
+	FuncCodecs   []string // produces= media types of functions without a built-in codec, checked by GogeRouter
+	UsesVersions bool     // some endpoint has a version and is also routed by Accept-Version
+	UsesCache    bool     // some endpoint has cache= or etag and replies through gogeSend
+	ExtraImports []string // import specs, e.g. `"strconv"` or `dto2 "example.com/app/v2/dto"`
 	Endpoints    []endpointVM
+	Services     []*serviceVM // one per receiver type with annotated methods
+}
+
+// serviceVM is the interface, handler and mock generated for one receiver type. A
+// package with a single receiver keeps the names Service, Handler, NewHandler and
+// ServiceMock; with several, they are named after each receiver, e.g. UserServiceAPI,
+// UserServiceHandler, NewUserServiceHandler and UserServiceMock.
+type serviceVM struct {
+	Recv      string // receiver type without "*"
+	Iface     string
+	Handler   string
+	Ctor      string
+	Mock      string
+	Arg       string // parameter name in RegisterServices
+	UsesAuth  bool
+	Codecs    []string // produces= media types without a built-in codec, checked by RegisterRoutes
+	Endpoints []endpointVM
+}
+
+// packageServices names the services of a package by receiver type.
+func packageServices(pkg *scanner.PackageAPIs) map[string]*serviceVM {
+	out := map[string]*serviceVM{}
+	for _, ep := range pkg.Endpoints {
+		if !ep.Func {
+			out[receiverType(ep.RecvName)] = nil
+		}
+	}
+	for recv := range out {
+		if len(out) == 1 {
+			out[recv] = &serviceVM{Recv: recv, Iface: "Service", Handler: "Handler", Ctor: "NewHandler", Mock: "ServiceMock"}
+			continue
+		}
+		name := exportName(recv)
+		arg := strings.ToLower(recv[:1]) + recv[1:]
+		if token.IsKeyword(arg) || reservedVars[arg] || arg == "app" || arg == "auth" {
+			arg += "Svc"
+		}
+		out[recv] = &serviceVM{Recv: recv, Iface: name + "API", Handler: name + "Handler", Ctor: "New" + name + "Handler", Mock: name + "Mock", Arg: arg}
+	}
+	return out
+}
+
+// receiverType strips the pointer and type parameters of a receiver, "*svc[T]" => "svc".
+func receiverType(recv string) string {
+	recv = strings.TrimPrefix(recv, "*")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return recv
 }
 
 var externalStructCache = struct {
 	sync.RWMutex
-	structs map[string]map[string]*ast.StructType
-	fails   map[string]bool
+	structs    map[string]map[string]*ast.StructType
+	imports    map[string]map[string]map[string]string // import path => struct => imports of its file
+	marshalers map[string]map[string]string            // import path => type => "json" or "text"
+	fails      map[string]bool
 }{
-	structs: map[string]map[string]*ast.StructType{},
-	fails:   map[string]bool{},
+	structs:    map[string]map[string]*ast.StructType{},
+	imports:    map[string]map[string]map[string]string{},
+	marshalers: map[string]map[string]string{},
+	fails:      map[string]bool{},
+}
+
+// Options controls what Generate writes besides handler_gen.go.
+type Options struct {
+	Tests bool   // write handler_gen_test.go with binding tests for every endpoint
+	Spec  string // OpenAPI document written under root, e.g. "openapi.json"; empty skips it
+	// SpecVersions also writes one document per version next to Spec, e.g.
+	// openapi.v2.json, with the endpoints of that version and the unversioned ones.
+	SpecVersions bool
+	Title        string // info.title of the OpenAPI document
+	Version      string // info.version of the OpenAPI document
+	Out          string // directory under root for the generated packages; empty writes them next to the services
 }
 
 // main entry
-func Generate(root string, apis map[string]*scanner.PackageAPIs) error {
+func Generate(root string, apis map[string]*scanner.PackageAPIs, opts Options) error {
 	fmt.Printf("[goge] generating handlers in root: %s\n", root)
+	resolveInputs(apis)
+	if err := Validate(root, apis); err != nil {
+		return err
+	}
+	if opts.Out != "" {
+		var errs []error
+		for _, dir := range sortedDirs(apis) {
+			errs = append(errs, checkOut(root, apis[dir])...)
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	var spec *specBuilder
+	if opts.Spec != "" {
+		spec = newSpecBuilder(root, apis, opts)
+	}
+	schemes := securitySchemes(apis)
+	fallbacks := versionFallbacks(apis)
+	successors := versionSuccessors(apis)
 
-	for pkgDir, pkg := range apis {
-		vm := pkgVM{
-			PkgName:      pkg.PkgName,
-			ExtraImports: collectImports(pkg),
+	for _, pkg := range apis {
+		imps := newImportSet(loadTypes(pkg.PkgDir))
+		tgt, err := newTarget(root, opts.Out, pkg, imps)
+		if err != nil {
+			return err
 		}
+		vm := pkgVM{PkgName: pkg.PkgName}
+		services := packageServices(pkg)
 
 		for _, ep := range pkg.Endpoints {
 			isManual := ep.ManualFunc != ""
+			// the signature types, qualified with the aliases of the generated files
+			file := ep.Pos.Filename
+			inType := imps.expr(file, ep.Imports, ep.InputTypeExpr)
+			retType := imps.expr(file, ep.Imports, ep.ReturnTypeExpr)
+			wsIn := imps.expr(file, ep.Imports, ep.WSIn)
+			wsOut := imps.expr(file, ep.Imports, ep.WSOut)
+			for _, typ := range []string{inType, retType, wsIn, wsOut} {
+				for _, ip := range imps.paths(typ) {
+					vm.ExtraImports = appendUnique(vm.ExtraImports, ip)
+				}
+			}
 			ev := endpointVM{
 				MethodName:    ep.MethodName,
 				HTTPMethod:    strings.Title(strings.ToLower(ep.HTTPMethod)),
 				Path:          ep.Path,
 				InputIsStruct: ep.InputIsStruct,
-				InputTypeExpr: ep.InputTypeExpr,
-				ReturnType:    ep.ReturnTypeExpr,
-				ReturnIsBytes: ep.ReturnTypeExpr == "[]byte",
+				InputTypeExpr: inType,
+				HasContext:    ep.HasContext,
+				ReturnType:    retType,
+				ReturnKind:    ep.ReturnKind,
+				ContentType:   ep.ContentType,
+				ReturnIsPtr:   strings.HasPrefix(retType, "*"),
+				Status:        ep.Status,
+				Stream:        ep.Stream,
 				ManualFunc:    ep.ManualFunc,
+				Func:          ep.Func,
+				HandlerName:   ep.MethodName,
+				Recv:          ep.RecvName,
+				Call:          "h.service." + ep.MethodName,
+				Version:       ep.Version,
+				BasePath:      ep.BasePath,
+			}
+			if ep.Version != "" {
+				ev.VersionFallback = fallbacks[versionKey(ep)] == ep.Version
+				vm.UsesVersions = true
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+			}
+			var svc *serviceVM
+			if ep.Func {
+				ev.HandlerName = ep.MethodName + "Handler"
+				ev.Call = ep.MethodName
+				vm.HasFuncs = true
+			} else {
+				svc = services[receiverType(ep.RecvName)]
+				ev.HandlerType = svc.Handler
+			}
+
+			if ep.HasContext {
+				vm.ExtraImports = appendUnique(vm.ExtraImports, "context")
+			}
+			if ep.Auth != "" {
+				scheme := schemes[ep.Auth]
+				ev.Auth = &scheme
+				ev.AuthCode = buildAuthCode(scheme, ep.Scopes)
+				vm.UsesAuth = true
+				if svc != nil {
+					svc.UsesAuth = true
+				}
+				vm.ExtraImports = appendUnique(vm.ExtraImports, "errors")
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+			}
+			if ep.Deprecated {
+				ev.DeprecationCode = buildDeprecationCode(ep, successors[versionKey(ep)+" "+ep.Version])
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
 			}
 
 			// detect if BodyParser needed
@@ -150,18 +607,26 @@ func Generate(root string, apis map[string]*scanner.PackageAPIs) error {
 			}
 
 			if ep.InputIsStruct {
-				paramIsPtr := strings.HasPrefix(ep.InputTypeExpr, "*")
-				base := strings.TrimPrefix(ep.InputTypeExpr, "*")
+				paramIsPtr := strings.HasPrefix(inType, "*")
 
-				ev.InputArg = "req " + ep.InputTypeExpr
-				ev.ReqAlloc = fmt.Sprintf("req := new(%s)", base)
+				ev.InputArg = "req " + inType
+				ev.ReqAlloc = fmt.Sprintf("req := new(%s)", strings.TrimPrefix(inType, "*"))
 
 				if !isManual {
 					// parse struct (local or imported)
-					st := findStructAST(root, pkg, base)
+					st := findStructAST(root, pkg, strings.TrimPrefix(ep.InputTypeExpr, "*"))
 					if st != nil {
 						binds := ExtractBindingsRecursive(pkg, st)
+						for _, ip := range imps.binds(pkg, binds) {
+							vm.ExtraImports = appendUnique(vm.ExtraImports, ip)
+						}
+						ev.Binds = binds
 						ev.BindingCode = BuildBindCode(binds)
+						ev.EnumCheckCode = BuildEnumCheckCode(binds)
+						ev.PrincipalCode = strings.TrimSuffix(buildPrincipalCode(binds), "\n")
+						if BindsNeedStrconv(binds) {
+							vm.ExtraImports = appendUnique(vm.ExtraImports, "strconv")
+						}
 					}
 				}
 
@@ -170,106 +635,374 @@ func Generate(root string, apis map[string]*scanner.PackageAPIs) error {
 				} else {
 					ev.CallArg = "*req"
 				}
-			} else {
-				name := pathParamName(ep.Path)
-				if name == "" {
-					name = "v"
-				}
-				ev.InputArg = fmt.Sprintf("%s %s", name, strings.TrimPrefix(ep.InputTypeExpr, "*"))
+			} else if ep.InputTypeExpr != "" {
+				key, name := primitiveParam(ep.Path)
+				ev.InputArg = fmt.Sprintf("%s %s", name, strings.TrimPrefix(inType, "*"))
 				ev.CallArg = name
 				if !isManual {
-					code, needStrconv := primitiveBind(name, ep.InputTypeExpr)
+					code, needStrconv := primitiveBind(key, name, inType, inputKind(imps.ti, file, ep.InputTypeExpr))
 					ev.PrimitiveBind = code
 					if needStrconv {
-						vm.ExtraImports = append(vm.ExtraImports, "strconv")
+						vm.ExtraImports = appendUnique(vm.ExtraImports, "strconv")
+					}
+				}
+			}
+
+			var respBinds []FieldBind
+			if !isManual && ep.ReturnKind == "" && isNamedType(ep.ReturnTypeExpr) {
+				if st := findStructAST(root, pkg, strings.TrimPrefix(ep.ReturnTypeExpr, "*")); st != nil {
+					binds := ExtractBindingsRecursive(pkg, st)
+					respBinds = binds
+					ev.RespCode = strings.TrimSuffix(BuildRespCode(binds), "\n")
+					if RespNeedsFmt(binds) {
+						vm.ExtraImports = appendUnique(vm.ExtraImports, "fmt")
+					}
+					if RespNeedsStrconv(binds) {
+						vm.ExtraImports = appendUnique(vm.ExtraImports, "strconv")
 					}
 				}
 			}
 
+			if _, ok := pageResultItem(ep); ok && !isManual && ep.Stream == "" {
+				ev.PageCode = buildPageLinksCode(ev.Binds, ep.InputIsStruct)
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+			}
+
+			if len(ep.Produces) > 0 && !isManual && ep.Stream == "" && ep.ReturnKind == "" {
+				quoted := make([]string, len(ep.Produces))
+				for i, mt := range ep.Produces {
+					quoted[i] = fmt.Sprintf("%q", mt)
+				}
+				ev.MediaTypes = strings.Join(quoted, ", ")
+				for _, mt := range ep.Produces {
+					if builtinCodecs[mt] {
+						continue
+					}
+					fmt.Printf("[goge] warning: %s: %s: no built-in codec for %s; register one with goge.RegisterCodec before registering the routes\n", ep.Pos, ep.MethodName, mt)
+					if svc != nil {
+						svc.Codecs = appendUnique(svc.Codecs, mt)
+					} else {
+						vm.FuncCodecs = appendUnique(vm.FuncCodecs, mt)
+					}
+				}
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+				if ev.NeedsBodyParser && ep.InputIsStruct {
+					vm.ExtraImports = appendUnique(vm.ExtraImports, "errors")
+				}
+			}
+
+			if ep.CacheMaxAge > 0 || ep.ETag {
+				ev.CacheControl = cacheControl(ep)
+				ev.ETag = ep.ETag
+				ev.CacheVary = cacheVary(ev)
+				switch {
+				case ep.ReturnKind == scanner.ReturnBytes:
+					ev.CacheContentType = fmt.Sprintf("%q", cmp.Or(ep.ContentType, "application/octet-stream"))
+				case ev.MediaTypes != "":
+					ev.CacheContentType = "mediaType"
+				default:
+					ev.CacheContentType = "fiber.MIMEApplicationJSON"
+				}
+				if storesResponses(ep) {
+					ev.CacheKey = buildCacheKey(ep, ev)
+					ev.CacheTTL = cacheTTL(ep.CacheMaxAge)
+					ev.CacheHeaders = cacheHeaders(ev, respBinds)
+					vm.ExtraImports = appendUnique(vm.ExtraImports, "time")
+				}
+				vm.UsesCache = true
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+			}
+
+			if ep.Stream == scanner.StreamWS {
+				ev.HTTPMethod = "Get"
+				ev.Params = fmt.Sprintf("ctx context.Context, in <-chan %s, out chan<- %s", wsIn, wsOut)
+				ev.ParamNames = []string{"ctx", "in", "out"}
+				ev.ParamTypes = []string{"context.Context", "<-chan " + wsIn, "chan<- " + wsOut}
+				ev.Results = "error"
+				ev.NamedResults = namedResults("")
+				vm.ExtraImports = appendUnique(vm.ExtraImports, "context")
+				vm.ExtraImports = appendUnique(vm.ExtraImports, wsImportPath)
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+				if spec != nil {
+					spec.add(pkg, ep, ev)
+				}
+				vm.Endpoints = append(vm.Endpoints, ev)
+				continue
+			}
+
+			if ep.Stream != "" && !isManual {
+				vm.ExtraImports = appendUnique(vm.ExtraImports, "bufio")
+				vm.ExtraImports = appendUnique(vm.ExtraImports, "context")
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+			}
+
+			params, args, names, types := []string{}, []string{}, []string{}, []string{}
+			if ep.FiberCtx {
+				params = append(params, "c *fiber.Ctx")
+				names = append(names, "c")
+				types = append(types, "*fiber.Ctx")
+				args = append(args, "c")
+			}
+			if ep.HasContext {
+				params = append(params, "ctx context.Context")
+				names = append(names, "ctx")
+				types = append(types, "context.Context")
+				if ep.Stream != "" {
+					// streams run past the handler; use the cancelable ctx
+					args = append(args, "ctx")
+				} else {
+					args = append(args, "c.UserContext()")
+				}
+			}
+			if ev.InputArg != "" {
+				params = append(params, ev.InputArg)
+				args = append(args, ev.CallArg)
+				names = append(names, strings.Fields(ev.InputArg)[0])
+				types = append(types, strings.SplitN(ev.InputArg, " ", 2)[1])
+			}
+			if ep.Stream == scanner.StreamSink {
+				params = append(params, "sink goge.EventWriter")
+				names = append(names, "sink")
+				types = append(types, "goge.EventWriter")
+				args = append(args, "sink")
+				vm.ExtraImports = appendUnique(vm.ExtraImports, scanner.RuntimeImportPath)
+			}
+			ev.Params = strings.Join(params, ", ")
+			ev.ParamNames = names
+			ev.ParamTypes = types
+			ev.NamedResults = namedResults(retType)
+			ev.CallArgs = strings.Join(args, ", ")
+			ev.Results = "error"
+			if retType != "" {
+				ev.Results = fmt.Sprintf("(%s, error)", retType)
+				if !isManual && ep.ReturnKind == "" && ep.Stream == "" {
+					vm.UsesResponse = true
+				}
+			}
+
+			if spec != nil {
+				spec.add(pkg, ep, ev)
+			}
 			vm.Endpoints = append(vm.Endpoints, ev)
 		}
 
-		sort.Slice(vm.Endpoints, func(i, j int) bool { return vm.Endpoints[i].MethodName < vm.Endpoints[j].MethodName })
-		sort.Strings(vm.ExtraImports)
+		sort.Slice(vm.Endpoints, func(i, j int) bool {
+			a, b := vm.Endpoints[i], vm.Endpoints[j]
+			if a.MethodName != b.MethodName {
+				return a.MethodName < b.MethodName
+			}
+			return a.HandlerType < b.HandlerType
+		})
+		for _, ev := range vm.Endpoints {
+			if !ev.Func {
+				svc := services[receiverType(ev.Recv)]
+				svc.Endpoints = append(svc.Endpoints, ev)
+			}
+		}
+		for _, svc := range services {
+			vm.Services = append(vm.Services, svc)
+		}
+		sort.Slice(vm.Services, func(i, j int) bool { return vm.Services[i].Handler < vm.Services[j].Handler })
+		vm.ExtraImports = imps.specs(vm.ExtraImports)
 
-		var buf bytes.Buffer
-		buf.WriteString(fileHeader)
-		if err := tpl.Execute(&buf, vm); err != nil {
-			return fmt.Errorf("template exec: %w", err)
+		if err := writeGoFile(tgt, "handler_gen.go", tpl, vm); err != nil {
+			return err
 		}
 
-		formatted, err := format.Source(buf.Bytes())
-		if err != nil {
-			return fmt.Errorf("format generated handler: %w", err)
+		if len(vm.Services) == 0 {
+			continue // GogeRouter only; nothing to mock or test through a Handler
 		}
-		out := filepath.Join(pkgDir, "handler_gen.go")
-		if err := os.WriteFile(out, formatted, 0o644); err != nil {
-			return fmt.Errorf("write %s: %w", out, err)
+		if err := generateMock(tgt, imps, vm); err != nil {
+			return err
 		}
+		if opts.Tests {
+			if err := generateTests(tgt, imps, vm); err != nil {
+				return err
+			}
+		}
+	}
+	if spec != nil {
+		return spec.write(filepath.Join(root, opts.Spec))
 	}
 	return nil
 }
 
-// --- Helpers ---
+func generateMock(tgt *target, imps *importSet, vm pkgVM) error {
+	mvm := mockPkgVM{
+		PkgName: vm.PkgName,
+		Imports: imps.specs(appendUnique(signatureImports(imps, vm), "sync")),
+	}
+	for _, svc := range vm.Services {
+		m := mockVM{Name: svc.Mock, Iface: svc.Iface}
+		for _, ev := range svc.Endpoints {
+			m.Endpoints = append(m.Endpoints, buildMockEndpoint(ev))
+		}
+		mvm.Mocks = append(mvm.Mocks, m)
+	}
+	return writeGoFile(tgt, "service_mock_gen.go", mockTpl, mvm)
+}
 
-func collectImports(pkg *scanner.PackageAPIs) []string {
-	need := map[string]bool{}
-	for _, ep := range pkg.Endpoints {
-		if i := strings.Index(ep.InputTypeExpr, "."); i > 0 {
-			alias := strings.TrimPrefix(ep.InputTypeExpr[:i], "*")
-			if ip, ok := pkg.Imports[alias]; ok {
-				need[ip] = true
+func generateTests(tgt *target, imps *importSet, vm pkgVM) error {
+	tvm := testPkgVM{
+		PkgName:  vm.PkgName,
+		Imports:  []string{"net/http", "net/http/httptest", "testing", "github.com/gofiber/fiber/v2"},
+		UsesAuth: vm.UsesAuth,
+	}
+	if vm.UsesAuth {
+		tvm.Imports = append(tvm.Imports, "context", scanner.RuntimeImportPath)
+	}
+	for _, svc := range vm.Services {
+		for _, ev := range svc.Endpoints {
+			for _, tc := range buildTestCases(ev) {
+				if tc.Body {
+					tvm.Imports = appendUnique(tvm.Imports, "strings")
+				}
+				tc.Mock, tc.Ctor, tc.Auth = svc.Mock, svc.Ctor, svc.UsesAuth
+				if len(vm.Services) > 1 {
+					tc.Name = exportName(svc.Recv) + tc.Name
+				}
+				tvm.Tests = append(tvm.Tests, tc)
 			}
 		}
 	}
-	out := make([]string, 0, len(need))
-	for k := range need {
-		out = append(out, k)
+	if len(tvm.Tests) == 0 {
+		return nil
 	}
-	return out
+	tvm.Imports = imps.specs(tvm.Imports)
+	return writeGoFile(tgt, "handler_gen_test.go", testTpl, tvm)
 }
 
-func pathParamName(path string) string {
-	i := strings.Index(path, ":")
-	if i < 0 {
-		return ""
+// signatureImports lists the packages referenced by the service method signatures.
+func signatureImports(imps *importSet, vm pkgVM) []string {
+	var imports []string
+	for _, svc := range vm.Services {
+		for _, ev := range svc.Endpoints {
+			for _, typ := range append([]string{ev.Results}, ev.ParamTypes...) {
+				for _, ip := range imps.paths(typ) {
+					imports = appendUnique(imports, ip)
+				}
+			}
+		}
 	}
-	j := i + 1
-	for j < len(path) && (isAlphaNum(path[j]) || path[j] == '_') {
-		j++
+	return imports
+}
+
+func writeGoFile(tgt *target, name string, t *template.Template, data any) error {
+	var buf bytes.Buffer
+	buf.WriteString(fileHeader)
+	if err := t.Execute(&buf, data); err != nil {
+		return fmt.Errorf("template exec %s: %w", name, err)
 	}
-	return path[i+1 : j]
+	return tgt.write(name, buf.Bytes())
 }
 
-func isAlphaNum(b byte) bool {
-	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+// --- Helpers ---
+
+// qualifierRe finds package qualifiers in type expressions such as "<-chan dto.Event".
+var qualifierRe = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.`)
+
+// isNamedType reports whether typ is `T`, `*T`, `pkg.T` or `*pkg.T`.
+func isNamedType(typ string) bool {
+	typ = strings.TrimPrefix(typ, "*")
+	if typ == "" || strings.ContainsAny(typ, "[]*(){} ") {
+		return false
+	}
+	return typ != "any" && typ != "string" && typ != "error"
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
 }
 
-// supports primitives like string/int only
-func primitiveBind(name, typ string) (string, bool) {
+// primitiveParam returns the c.Params key a primitive input binds to, the path's only
+// parameter or "v" from the query, and the Go variable name used for it. Validate rejects
+// primitive inputs on paths with more than one parameter.
+func primitiveParam(path string) (key, name string) {
+	keys := parseRoute("", path).Keys()
+	if len(keys) == 0 {
+		return "v", "v"
+	}
+	key = keys[0]
+	name = wildcardName(key)
+	if token.IsKeyword(name) || reservedVars[name] || name[0] >= '0' && name[0] <= '9' {
+		name += "Param"
+	}
+	return key, name
+}
+
+// reservedVars are identifiers the generated handler body already uses.
+// builtinCodecs are the produces= media types goge encodes without goge.RegisterCodec.
+var builtinCodecs = map[string]bool{"application/json": true, "application/xml": true}
+
+var reservedVars = map[string]bool{
+	"c": true, "h": true, "ctx": true, "cancel": true, "req": true, "res": true, "err": true,
+	"body": true, "mediaType": true, "contentType": true, "sink": true,
+	"fiber": true, "strconv": true, "context": true, "goge": true, "response": true,
+}
+
+// primitiveParsers maps numeric and bool inputs to their strconv parser, the bit size
+// passed to it and the zero value used when the parameter is missing.
+var primitiveParsers = map[string]struct{ parse, bits, zero string }{
+	"int":     {"Atoi", "", "0"},
+	"int8":    {"ParseInt", "8", "0"},
+	"int16":   {"ParseInt", "16", "0"},
+	"int32":   {"ParseInt", "32", "0"},
+	"int64":   {"ParseInt", "64", "0"},
+	"uint":    {"ParseUint", "0", "0"},
+	"uint8":   {"ParseUint", "8", "0"},
+	"uint16":  {"ParseUint", "16", "0"},
+	"uint32":  {"ParseUint", "32", "0"},
+	"uint64":  {"ParseUint", "64", "0"},
+	"float32": {"ParseFloat", "32", "0"},
+	"float64": {"ParseFloat", "64", "0"},
+	"bool":    {"ParseBool", "", "false"},
+}
+
+// primitiveBind reads key from the path, falling back to the query, into the variable name.
+// Kind is the inputKind of typ.
+func primitiveBind(key, name, typ, kind string) (string, bool) {
 	typ = strings.TrimPrefix(typ, "*")
-	switch typ {
-	case "string":
-		return fmt.Sprintf("%s := c.Params(%q, c.Query(%q, \"\"))", name, name, name), false
-	case "int", "int64":
-		return fmt.Sprintf(`
-		{
-			raw := c.Params("%[1]s", c.Query("%[1]s", "0"))
-			val, err := strconv.ParseInt(raw, 10, 64)
-			if err != nil { return fiber.ErrBadRequest }
-			%[1]s := %[2]s(val)
-		}`, name, typ), true
-	case "float64":
-		return fmt.Sprintf(`
-		{
-			raw := c.Params("%[1]s", c.Query("%[1]s", "0"))
-			val, err := strconv.ParseFloat(raw, 64)
-			if err != nil { return fiber.ErrBadRequest }
-			%[1]s := val
-		}`, name), true
-	default:
-		return fmt.Sprintf("%s := c.Params(%q, c.Query(%q, \"\"))", name, name, name), false
+	if kind == inputText {
+		// e.g. uuid.UUID or netip.Addr
+		return fmt.Sprintf(`var %[1]s %[2]s
+		if err := %[1]s.UnmarshalText([]byte(c.Params(%[3]q, c.Query(%[3]q)))); err != nil {
+			return fiber.ErrBadRequest
+		}`, name, typ, key), false
 	}
+	p, ok := primitiveParsers[kind]
+	if !ok {
+		if typ == "string" {
+			return fmt.Sprintf("%s := c.Params(%q, c.Query(%q))", name, key, key), false
+		}
+		// named string types such as `type userID string`
+		return fmt.Sprintf("%s := %s(c.Params(%q, c.Query(%q)))", name, typ, key, key), false
+	}
+
+	raw := fmt.Sprintf("c.Params(%q, c.Query(%q, %q))", key, key, p.zero)
+	args := raw
+	switch p.parse {
+	case "ParseInt", "ParseUint":
+		args += ", 10, " + p.bits
+	case "ParseFloat":
+		args += ", " + p.bits
+	}
+	// strconv returns int64/uint64/float64 except for Atoi and ParseBool
+	if typ == kind && (typ == "int" || typ == "bool" || typ == "int64" || typ == "uint64" || typ == "float64") {
+		return fmt.Sprintf(`%s, err := strconv.%s(%s)
+		if err != nil {
+			return fiber.ErrBadRequest
+		}`, name, p.parse, args), true
+	}
+	return fmt.Sprintf(`%[1]sVal, err := strconv.%[2]s(%[3]s)
+		if err != nil {
+			return fiber.ErrBadRequest
+		}
+		%[1]s := %[4]s(%[1]sVal)`, name, p.parse, args, typ), true
 }
 
 // --- AST parsing helpers ---
@@ -280,6 +1013,7 @@ type astStruct struct {
 	pkgDir     string
 	ImportPath string
 	moduleDir  string
+	imports    map[string]string // alias => import path in the file declaring the struct
 	loader     func(name string) *astStruct
 }
 
@@ -308,10 +1042,11 @@ func parseStructAST(pkgDir, structName string) *astStruct {
 				if st, ok := ts.Type.(*ast.StructType); ok {
 					loader := func(name string) *astStruct { return parseStructAST(pkgDir, name) }
 					return &astStruct{
-						Name:   structName,
-						Struct: st,
-						pkgDir: pkgDir,
-						loader: loader,
+						Name:    structName,
+						Struct:  st,
+						pkgDir:  pkgDir,
+						imports: fileImports(node),
+						loader:  loader,
 					}
 				}
 			}
@@ -327,6 +1062,31 @@ func (a *astStruct) Fields() []*ast.Field {
 	return a.Struct.Fields.List
 }
 
+// importOf resolves a package qualifier used by the struct's fields. Without a struct it
+// falls back to the imports of the service package.
+func (a *astStruct) importOf(pkg *scanner.PackageAPIs, name string) (string, bool) {
+	if a == nil {
+		ip, ok := pkg.Imports[name]
+		return ip, ok
+	}
+	ip, ok := a.imports[name]
+	return ip, ok
+}
+
+// fileImports maps the import names of f to their paths.
+func fileImports(f *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, im := range f.Imports {
+		ip := strings.Trim(im.Path.Value, `"`)
+		if im.Name != nil {
+			imports[im.Name.Name] = ip
+		} else {
+			imports[scanner.ImportName(ip)] = ip
+		}
+	}
+	return imports
+}
+
 func (a *astStruct) load(name string) *astStruct {
 	if a == nil || name == "" || a.loader == nil {
 		return nil
@@ -372,20 +1132,30 @@ func loadStructFromImport(importPath, structName, moduleDir string) *astStruct {
 	if importPath == "" || structName == "" {
 		return nil
 	}
-	loader := func(name string) *astStruct { return loadStructFromImport(importPath, name, moduleDir) }
+	structs := indexImport(importPath, moduleDir)
+	st, ok := structs[structName]
+	if !ok {
+		return nil
+	}
+	externalStructCache.RLock()
+	imports := externalStructCache.imports[importPath][structName]
+	externalStructCache.RUnlock()
+	return &astStruct{
+		Name:       structName,
+		Struct:     st,
+		ImportPath: importPath,
+		moduleDir:  moduleDir,
+		imports:    imports,
+		loader:     func(name string) *astStruct { return loadStructFromImport(importPath, name, moduleDir) },
+	}
+}
 
+// indexImport loads an imported package once and caches its structs and enums.
+func indexImport(importPath, moduleDir string) map[string]*ast.StructType {
 	externalStructCache.RLock()
 	if structs, ok := externalStructCache.structs[importPath]; ok {
-		if st, ok := structs[structName]; ok {
-			externalStructCache.RUnlock()
-			return &astStruct{
-				Name:       structName,
-				Struct:     st,
-				ImportPath: importPath,
-				moduleDir:  moduleDir,
-				loader:     loader,
-			}
-		}
+		externalStructCache.RUnlock()
+		return structs
 	}
 	if externalStructCache.fails[importPath] {
 		externalStructCache.RUnlock()
@@ -397,15 +1167,7 @@ func loadStructFromImport(importPath, structName, moduleDir string) *astStruct {
 	defer externalStructCache.Unlock()
 
 	if structs, ok := externalStructCache.structs[importPath]; ok {
-		if st, ok := structs[structName]; ok {
-			return &astStruct{
-				Name:       structName,
-				Struct:     st,
-				ImportPath: importPath,
-				moduleDir:  moduleDir,
-				loader:     loader,
-			}
-		}
+		return structs
 	}
 	if externalStructCache.fails[importPath] {
 		return nil
@@ -433,8 +1195,12 @@ func loadStructFromImport(importPath, structName, moduleDir string) *astStruct {
 	}
 
 	structs := make(map[string]*ast.StructType)
+	imports := make(map[string]map[string]string)
+	enums := make(map[string]*enumType)
+	methods := make(map[string]string)
 	for _, p := range pkgs {
 		for _, f := range p.Syntax {
+			fimports := fileImports(f)
 			for _, decl := range f.Decls {
 				genDecl, ok := decl.(*ast.GenDecl)
 				if !ok {
@@ -447,23 +1213,22 @@ func loadStructFromImport(importPath, structName, moduleDir string) *astStruct {
 					}
 					if st, ok := ts.Type.(*ast.StructType); ok {
 						structs[ts.Name.Name] = st
+						imports[ts.Name.Name] = fimports
 					}
 				}
 			}
 		}
+		maps.Copy(enums, collectEnums(p.Fset, p.Syntax))
+		maps.Copy(methods, marshalers(p.Syntax))
 	}
 
 	externalStructCache.structs[importPath] = structs
-	if st, ok := structs[structName]; ok {
-		return &astStruct{
-			Name:       structName,
-			Struct:     st,
-			ImportPath: importPath,
-			moduleDir:  moduleDir,
-			loader:     loader,
-		}
-	}
-	return nil
+	externalStructCache.imports[importPath] = imports
+	externalStructCache.marshalers[importPath] = methods
+	enumCache.Lock()
+	enumCache.enums[importPath] = enums
+	enumCache.Unlock()
+	return structs
 }
 
 // findStructAST resolves both local and imported structs using the explicit import paths collected for a package.
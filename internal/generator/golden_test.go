@@ -0,0 +1,240 @@
+package generator
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestGolden runs scanner.Scan and Generate over every testdata/golden/<case>/input
+// package, type-checks the result and compares each generated file with
+// testdata/golden/<case>/want/<file>.golden.
+func TestGolden(t *testing.T) {
+	cases, err := os.ReadDir(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		if !c.IsDir() {
+			continue
+		}
+		t.Run(c.Name(), func(t *testing.T) {
+			runGoldenCase(t, filepath.Join("testdata", "golden", c.Name()))
+		})
+	}
+}
+
+func runGoldenCase(t *testing.T, caseDir string) {
+	dir := t.TempDir()
+	inputs, err := filepath.Glob(filepath.Join(caseDir, "input", "*.go"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no input files in %s", caseDir)
+	}
+	for _, in := range inputs {
+		copyFile(t, in, filepath.Join(dir, filepath.Base(in)))
+	}
+
+	opts := Options{Tests: true, Spec: "openapi.json"}
+	local := map[string]string{}
+	if _, err := os.Stat(filepath.Join(caseDir, "input", "go.mod")); err == nil {
+		// a module of its own, so the generator has type information
+		copyFile(t, filepath.Join(caseDir, "input", "go.mod"), filepath.Join(dir, "go.mod"))
+	}
+	if b, err := os.ReadFile(filepath.Join(caseDir, "out")); err == nil {
+		// generate into another package of a module whose root is the input
+		opts.Out = strings.TrimSpace(string(b))
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+goldenModule+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		local[goldenModule] = dir
+	}
+
+	apis, err := scanner.Scan(dir)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if err := Generate(dir, apis, opts); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	outDir := filepath.Join(dir, opts.Out)
+	generated, err := filepath.Glob(filepath.Join(outDir, "*_gen*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated = append(generated, filepath.Join(dir, "openapi.json"))
+	typeCheck(t, outDir, local)
+
+	wantDir := filepath.Join(caseDir, "want")
+	if *update {
+		os.RemoveAll(wantDir)
+		if err := os.MkdirAll(wantDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seen := map[string]bool{}
+	for _, g := range generated {
+		name := filepath.Base(g) + ".golden"
+		seen[name] = true
+		got, err := os.ReadFile(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join(wantDir, name)
+		if *update {
+			if err := os.WriteFile(golden, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("missing golden %s (run go test -update): %v", golden, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s differs from golden (run go test -update to accept):\n%s", filepath.Base(g), diffLines(string(want), string(got)))
+		}
+	}
+	goldens, _ := filepath.Glob(filepath.Join(wantDir, "*.golden"))
+	for _, g := range goldens {
+		if !seen[filepath.Base(g)] {
+			t.Errorf("golden %s was not generated", filepath.Base(g))
+		}
+	}
+}
+
+// goldenModule is the module of cases with an out file, which name the directory
+// Options.Out.
+const goldenModule = "example.com/golden"
+
+// typeCheck compiles every .go file in dir (tests included) with go/types. Imports
+// resolve to the directories in local, to testdata/stubs when a stub exists and to the
+// goge module (fiber, pkg/goge, the standard library) otherwise.
+func typeCheck(t *testing.T, dir string, local map[string]string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	files := []*ast.File{}
+	for _, p := range paths {
+		f, err := parser.ParseFile(fset, p, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", filepath.Base(p), err)
+		}
+		files = append(files, f)
+	}
+
+	imp := newGoldenImporter(t, fset)
+	imp.local = local
+	conf := types.Config{Importer: imp}
+	if _, err := conf.Check(files[0].Name.Name, fset, files, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v", err)
+	}
+}
+
+type goldenImporter struct {
+	t     *testing.T
+	fset  *token.FileSet
+	stubs string
+	std   types.Importer // export data via the go command, resolved within this module
+	local map[string]string
+	pkgs  map[string]*types.Package
+}
+
+func newGoldenImporter(t *testing.T, fset *token.FileSet) *goldenImporter {
+	stubs, err := filepath.Abs(filepath.Join("testdata", "stubs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &goldenImporter{t: t, fset: fset, stubs: stubs, std: importer.ForCompiler(fset, "gc", exportLookup), pkgs: map[string]*types.Package{}}
+}
+
+func (g *goldenImporter) Import(path string) (*types.Package, error) {
+	if p, ok := g.pkgs[path]; ok {
+		return p, nil
+	}
+	stubDir, ok := g.local[path]
+	if !ok {
+		stubDir = filepath.Join(g.stubs, filepath.FromSlash(path))
+	}
+	if st, err := os.Stat(stubDir); err == nil && st.IsDir() {
+		paths, _ := filepath.Glob(filepath.Join(stubDir, "*.go"))
+		files := []*ast.File{}
+		for _, p := range paths {
+			f, err := parser.ParseFile(g.fset, p, nil, 0)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+		conf := types.Config{Importer: g}
+		pkg, err := conf.Check(path, g.fset, files, nil)
+		if err != nil {
+			return nil, fmt.Errorf("stub %s: %w", path, err)
+		}
+		g.pkgs[path] = pkg
+		return pkg, nil
+	}
+
+	pkg, err := g.std.Import(path)
+	if err != nil {
+		return nil, err
+	}
+	g.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// exportLookup finds compiler export data with "go list -export", run from this package
+// so that module dependencies resolve.
+func exportLookup(path string) (io.ReadCloser, error) {
+	out, err := exec.Command("go", "list", "-export", "-f", "{{.Export}}", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list %s: %w", path, err)
+	}
+	return os.Open(strings.TrimSpace(string(out)))
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	b, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// diffLines reports the first differing lines, enough to locate a golden mismatch.
+func diffLines(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var sb strings.Builder
+	shown := 0
+	for i := 0; i < max(len(wl), len(gl)) && shown < 10; i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			fmt.Fprintf(&sb, "line %d:\n  want: %s\n  got:  %s\n", i+1, w, g)
+			shown++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,389 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// typeInfo is a service package type-checked against the export data of its
+// dependencies. It resolves the qualifiers of type expressions to packages and tells how
+// a primitive input is parsed. Loading fails outside a module or when dependencies do
+// not build; callers then fall back to the import declarations the scanner collected.
+type typeInfo struct {
+	fset  *token.FileSet
+	pkg   *types.Package
+	files map[string]*ast.File // by file name
+}
+
+var typesCache = struct {
+	sync.Mutex
+	infos map[string]*typeInfo
+}{infos: map[string]*typeInfo{}}
+
+// loadTypes type-checks the package in dir once, ignoring its tests and generated files.
+// Type errors are tolerated: the package may refer to code that is generated later.
+func loadTypes(dir string) *typeInfo {
+	typesCache.Lock()
+	defer typesCache.Unlock()
+	if ti, ok := typesCache.infos[dir]; ok {
+		return ti
+	}
+	ti := checkTypes(dir)
+	typesCache.infos[dir] = ti
+	return ti
+}
+
+func checkTypes(dir string) *typeInfo {
+	if findModuleRoot(dir) == "" {
+		return nil
+	}
+	exports := exportFiles(dir)
+	if exports == nil {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	ti := &typeInfo{fset: fset, files: map[string]*ast.File{}}
+	var files []*ast.File
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") || strings.HasSuffix(p, "_gen.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+		ti.files[p] = f
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	lookup := func(path string) (io.ReadCloser, error) {
+		file := exports[path]
+		if file == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(file)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup), Error: func(error) {}}
+	ti.pkg, _ = conf.Check(files[0].Name.Name, fset, files, nil)
+	if ti.pkg == nil {
+		return nil
+	}
+	return ti
+}
+
+// exportFiles maps the dependencies of the package in dir to their compiler export data.
+func exportFiles(dir string) map[string]string {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedExportFile,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil || len(pkgs) == 0 {
+		return nil
+	}
+	exports := map[string]string{}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if p.ExportFile != "" {
+			exports[p.PkgPath] = p.ExportFile
+		}
+	})
+	return exports
+}
+
+// importOf returns the path of the package name refers to in file, or "".
+func (ti *typeInfo) importOf(file, name string) string {
+	if ti == nil {
+		return ""
+	}
+	f, ok := ti.files[file]
+	if !ok {
+		return ""
+	}
+	scope := ti.pkg.Scope().Innermost(f.Package)
+	if scope == nil {
+		return ""
+	}
+	if pn, ok := scope.Lookup(name).(*types.PkgName); ok {
+		return pn.Imported().Path()
+	}
+	return ""
+}
+
+// eval returns the type expr denotes in file, or nil.
+func (ti *typeInfo) eval(file, expr string) types.Type {
+	if ti == nil {
+		return nil
+	}
+	pos := token.NoPos
+	if f, ok := ti.files[file]; ok {
+		pos = f.Package
+	}
+	tv, err := types.Eval(ti.fset, ti.pkg, pos, expr)
+	if err != nil || !tv.IsType() {
+		return nil
+	}
+	return tv.Type
+}
+
+// constant returns the value of the constant expr denotes in file, or nil.
+func (ti *typeInfo) constant(file, expr string) constant.Value {
+	if ti == nil {
+		return nil
+	}
+	pos := token.NoPos
+	if f, ok := ti.files[file]; ok {
+		pos = f.Package
+	}
+	tv, err := types.Eval(ti.fset, ti.pkg, pos, expr)
+	if err != nil {
+		return nil
+	}
+	return tv.Value
+}
+
+// resolveInputs corrects the scanner's guess of which inputs are DTOs: with type
+// information, `Years` (an int), `uuid.UUID` and `netip.Addr` (TextUnmarshalers) are
+// primitive inputs read from the path or query rather than DTOs.
+func resolveInputs(apis map[string]*scanner.PackageAPIs) {
+	for _, pkg := range apis {
+		ti := loadTypes(pkg.PkgDir)
+		for i := range pkg.Endpoints {
+			ep := &pkg.Endpoints[i]
+			if ep.InputTypeExpr == "" {
+				continue
+			}
+			if t := ti.eval(ep.Pos.Filename, strings.TrimPrefix(ep.InputTypeExpr, "*")); t != nil {
+				_, isStruct := t.Underlying().(*types.Struct)
+				ep.InputIsStruct = isStruct && !types.Implements(types.NewPointer(t), textUnmarshaler)
+			}
+		}
+	}
+}
+
+// textUnmarshaler is encoding.TextUnmarshaler.
+var textUnmarshaler = types.NewInterfaceType([]*types.Func{
+	types.NewFunc(token.NoPos, nil, "UnmarshalText", types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(types.NewParam(token.NoPos, nil, "text", types.NewSlice(types.Typ[types.Byte]))),
+		types.NewTuple(types.NewParam(token.NoPos, nil, "", types.Universe.Lookup("error").Type())), false)),
+}, nil).Complete()
+
+// inputText marks primitive inputs parsed with UnmarshalText.
+const inputText = "text"
+
+// inputKind tells how the primitive input typ is read from a request string: the name of
+// its underlying basic type ("string", "int64", ...), inputText for an
+// encoding.TextUnmarshaler, or "" when it cannot be read. Without type information local
+// named types are taken to be strings and imported ones TextUnmarshalers.
+func inputKind(ti *typeInfo, file, typ string) string {
+	typ = strings.TrimPrefix(typ, "*")
+	if _, ok := primitiveParsers[typ]; ok || typ == "string" {
+		return typ
+	}
+	t := ti.eval(file, typ)
+	if t == nil {
+		if strings.Contains(typ, ".") {
+			return inputText
+		}
+		return "string"
+	}
+	if b, ok := t.Underlying().(*types.Basic); ok {
+		name := types.Typ[b.Kind()].Name()
+		if _, ok := primitiveParsers[name]; ok || name == "string" {
+			return name
+		}
+	}
+	if types.Implements(types.NewPointer(t), textUnmarshaler) {
+		return inputText
+	}
+	return ""
+}
+
+// importSet assigns the aliases the generated files of one package import packages
+// under. An alias taken by another package, or by an identifier of the generated code,
+// gets a numeric suffix: dto, dto2, ...
+type importSet struct {
+	ti      *typeInfo
+	byPath  map[string]string
+	byAlias map[string]string
+}
+
+// generatedImports are imported by the generated code under their default names.
+var generatedImports = []string{
+	"bufio", "context", "errors", "fmt", "net/http", "net/http/httptest", "strconv", "strings",
+	"sync", "testing", "time", "gaas/pkg/response", scanner.FiberImportPath, scanner.RuntimeImportPath, wsImportPath,
+}
+
+func newImportSet(ti *typeInfo) *importSet {
+	s := &importSet{ti: ti, byPath: map[string]string{}, byAlias: map[string]string{}}
+	for _, path := range generatedImports {
+		name := scanner.ImportName(path)
+		s.byPath[path], s.byAlias[name] = name, path
+	}
+	return s
+}
+
+// add returns the alias of path, allocating one from preferred on first use.
+func (s *importSet) add(path, preferred string) string {
+	if alias, ok := s.byPath[path]; ok {
+		return alias
+	}
+	alias := preferred
+	for i := 2; s.byAlias[alias] != "" || reservedVars[alias]; i++ {
+		alias = preferred + strconv.Itoa(i)
+	}
+	s.byPath[path] = alias
+	s.byAlias[alias] = path
+	return alias
+}
+
+// expr rewrites the package qualifiers of a type expression written in file to the
+// aliases of the set. Qualifiers resolve through the file's scope when type information
+// is available and through imports otherwise.
+func (s *importSet) expr(file string, imports map[string]string, expr string) string {
+	if expr == "" || !strings.Contains(expr, ".") {
+		return expr
+	}
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return expr
+	}
+	changed := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		id, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		path := s.ti.importOf(file, id.Name)
+		if path == "" {
+			path = imports[id.Name]
+		}
+		if path == "" {
+			return false
+		}
+		if alias := s.add(path, id.Name); alias != id.Name {
+			id.Name = alias
+			changed = true
+		}
+		return false
+	})
+	if !changed {
+		return expr
+	}
+	return types.ExprString(e)
+}
+
+// binds qualifies the binding types of a DTO with the aliases of the set and returns the
+// packages they refer to. Types declared by a DTO from another package are qualified
+// with that package even when the service package does not import it.
+func (s *importSet) binds(pkg *scanner.PackageAPIs, binds []FieldBind) []string {
+	var out []string
+	for i, b := range binds {
+		imports := pkg.Imports
+		if b.TypeImport != "" {
+			imports = maps.Clone(pkg.Imports)
+			imports[importAlias(pkg, b.TypeImport)] = b.TypeImport
+		}
+		binds[i].TypeExpr = s.expr("", imports, b.TypeExpr)
+		for _, ip := range s.paths(binds[i].TypeExpr) {
+			out = appendUnique(out, ip)
+		}
+	}
+	return out
+}
+
+// paths returns the import paths expr refers to, once rewritten by s.expr.
+func (s *importSet) paths(expr string) []string {
+	var out []string
+	for _, m := range qualifierRe.FindAllStringSubmatch(expr, -1) {
+		if path, ok := s.byAlias[m[1]]; ok {
+			out = appendUnique(out, path)
+		}
+	}
+	return out
+}
+
+// specs turns import paths into sorted import specs, naming the imports whose alias is
+// not the name the package is assumed to declare.
+func (s *importSet) specs(paths []string) []string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	out := make([]string, 0, len(sorted))
+	for _, path := range sorted {
+		if alias, ok := s.byPath[path]; ok && alias != scanner.ImportName(path) {
+			out = append(out, alias+" "+strconv.Quote(path))
+			continue
+		}
+		out = append(out, strconv.Quote(path))
+	}
+	return out
+}
+
+// pruneImports drops the imports a generated file does not use, such as the DTO package
+// of a function whose result is only passed on to the response.
+func pruneImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	changed := false
+	for _, spec := range append([]*ast.ImportSpec(nil), f.Imports...) {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := scanner.ImportName(path)
+		alias := ""
+		if spec.Name != nil {
+			name, alias = spec.Name.Name, spec.Name.Name
+		}
+		if name == "_" || name == "." || used[name] {
+			continue
+		}
+		astutil.DeleteNamedImport(fset, f, alias, path)
+		changed = true
+	}
+	if !changed {
+		return src, nil
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
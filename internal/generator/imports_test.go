@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportSet(t *testing.T) {
+	s := newImportSet(nil)
+	a := map[string]string{"dto": "example.com/app/dto"}
+	b := map[string]string{"dto": "example.com/legacy/dto", "req": "example.com/app/req"}
+
+	for _, tc := range []struct {
+		imports    map[string]string
+		expr, want string
+	}{
+		{a, "*dto.User", "*dto.User"},
+		{b, "[]dto.Tag", "[]dto2.Tag"},
+		{a, "map[string]dto.User", "map[string]dto.User"},
+		{b, "<-chan dto.Event", "<-chan dto2.Event"},
+		{b, "*req.Body", "*req2.Body"}, // req is a variable of the generated handlers
+		{b, "other.Type", "other.Type"},
+		{b, "string", "string"},
+	} {
+		if got := s.expr("", tc.imports, tc.expr); got != tc.want {
+			t.Errorf("expr(%q) = %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+
+	got := strings.Join(s.specs([]string{"strconv", "example.com/legacy/dto", "example.com/app/dto", "example.com/app/req"}), "; ")
+	want := `"example.com/app/dto"; req2 "example.com/app/req"; dto2 "example.com/legacy/dto"; "strconv"`
+	if got != want {
+		t.Errorf("specs = %s, want %s", got, want)
+	}
+	if p := s.paths("map[dto2.Key]*dto.User"); len(p) != 2 || p[0] != "example.com/legacy/dto" || p[1] != "example.com/app/dto" {
+		t.Errorf("paths = %v", p)
+	}
+}
+
+func TestPruneImports(t *testing.T) {
+	src := `package svc
+
+import (
+	"context"
+	"strconv"
+	dto2 "example.com/legacy/dto"
+	"example.com/app/dto"
+	"github.com/gofiber/fiber/v2"
+)
+
+func Handle(c *fiber.Ctx, req *dto2.Req) error { return nil }
+`
+	out, err := pruneImports([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kept := range []string{`dto2 "example.com/legacy/dto"`, `"github.com/gofiber/fiber/v2"`} {
+		if !strings.Contains(string(out), kept) {
+			t.Errorf("%s was dropped:\n%s", kept, out)
+		}
+	}
+	for _, dropped := range []string{`"context"`, `"strconv"`, `"example.com/app/dto"`} {
+		if strings.Contains(string(out), dropped) {
+			t.Errorf("%s was kept:\n%s", dropped, out)
+		}
+	}
+}
+
+func TestInputKind(t *testing.T) {
+	// without type information
+	for typ, want := range map[string]string{
+		"int64":      "int64",
+		"*string":    "string",
+		"userID":     "string",
+		"uuid.UUID":  inputText,
+		"netip.Addr": inputText,
+	} {
+		if got := inputKind(nil, "", typ); got != want {
+			t.Errorf("inputKind(%q) = %q, want %q", typ, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var mockTpl = template.Must(template.New("mock").Parse(`
+	package {{.PkgName}}
+
+	import (
+		{{- range .Imports }}
+			{{ . }}
+		{{- end }}
+	)
+
+	{{- range .Mocks }}
+	{{- $mock := .Name }}
+
+	// {{ $mock }} is a configurable {{ .Iface }} for tests. Set a <Method>Func field to control
+	// what a method returns; unset methods return zero values. Every call is recorded and
+	// can be inspected with <Method>Calls.
+	type {{ $mock }} struct {
+		{{- range .Endpoints }}
+		{{ .MethodName }}Func func({{ .Params }}) {{ .Results }}
+		{{- end }}
+
+		mu    sync.Mutex
+		calls struct {
+			{{- range .Endpoints }}
+			{{ .MethodName }} []{{ $mock }}{{ .MethodName }}Call
+			{{- end }}
+		}
+	}
+
+	var _ {{ .Iface }} = (*{{ $mock }})(nil)
+
+	{{- range .Endpoints }}
+	{{- $m := .MethodName }}
+
+	// {{ $mock }}{{ $m }}Call holds the arguments of one {{ $m }} call.
+	type {{ $mock }}{{ $m }}Call struct {
+		{{- range .Fields }}
+		{{ .Name }} {{ .Type }}
+		{{- end }}
+	}
+
+	func (mock *{{ $mock }}) {{ $m }}({{ .Params }}) {{ .NamedResults }} {
+		mock.mu.Lock()
+		mock.calls.{{ $m }} = append(mock.calls.{{ $m }}, {{ $mock }}{{ $m }}Call{ {{- range $i, $f := .Fields }}{{ if $i }}, {{ end }}{{ $f.Name }}: {{ $f.Arg }}{{ end -}} })
+		fn := mock.{{ $m }}Func
+		mock.mu.Unlock()
+		if fn != nil {
+			return fn({{ .Args }})
+		}
+		return
+	}
+
+	// {{ $m }}Calls returns the recorded calls to {{ $m }}.
+	func (mock *{{ $mock }}) {{ $m }}Calls() []{{ $mock }}{{ $m }}Call {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+		return append([]{{ $mock }}{{ $m }}Call(nil), mock.calls.{{ $m }}...)
+	}
+	{{- end }}
+	{{- end }}
+`))
+
+type mockFieldVM struct {
+	Name string // exported field name, e.g. Req
+	Type string
+	Arg  string // parameter name
+}
+
+type mockEndpointVM struct {
+	MethodName   string
+	Params       string
+	Results      string
+	NamedResults string
+	Args         string
+	Fields       []mockFieldVM
+}
+
+type mockVM struct {
+	Name      string // e.g. ServiceMock
+	Iface     string // the interface it implements, e.g. Service
+	Endpoints []mockEndpointVM
+}
+
+type mockPkgVM struct {
+	PkgName string
+	Imports []string
+	Mocks   []mockVM
+}
+
+func buildMockEndpoint(ev endpointVM) mockEndpointVM {
+	m := mockEndpointVM{
+		MethodName:   ev.MethodName,
+		Params:       ev.Params,
+		Results:      ev.Results,
+		NamedResults: ev.NamedResults,
+		Args:         strings.Join(ev.ParamNames, ", "),
+	}
+	for i, name := range ev.ParamNames {
+		m.Fields = append(m.Fields, mockFieldVM{Name: exportName(name), Type: ev.ParamTypes[i], Arg: name})
+	}
+	return m
+}
+
+// exportName upper-cases the first letter of a parameter name ("req" => "Req", "id" => "ID").
+func exportName(name string) string {
+	if strings.EqualFold(name, "id") {
+		return "ID"
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
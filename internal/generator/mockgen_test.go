@@ -0,0 +1,21 @@
+package generator
+
+import "testing"
+
+func TestBuildMockEndpoint(t *testing.T) {
+	m := buildMockEndpoint(endpointVM{
+		MethodName:   "GetUser",
+		Params:       "ctx context.Context, id string",
+		Results:      "(*User, error)",
+		NamedResults: "(res *User, err error)",
+		ParamNames:   []string{"ctx", "id"},
+		ParamTypes:   []string{"context.Context", "string"},
+	})
+	if m.Args != "ctx, id" {
+		t.Fatalf("args: %q", m.Args)
+	}
+	want := []mockFieldVM{{"Ctx", "context.Context", "ctx"}, {"ID", "string", "id"}}
+	if len(m.Fields) != len(want) || m.Fields[0] != want[0] || m.Fields[1] != want[1] {
+		t.Fatalf("fields: %+v", m.Fields)
+	}
+}
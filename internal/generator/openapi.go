@@ -0,0 +1,592 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// OpenAPI 3.0 document model, limited to what goge fills in.
+type openAPIDoc struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       openAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components *components                      `json:"components,omitempty"`
+}
+
+type components struct {
+	Schemas         map[string]*schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type             string      `json:"type"`
+	Description      string      `json:"description,omitempty"`
+	Scheme           string      `json:"scheme,omitempty"`
+	BearerFormat     string      `json:"bearerFormat,omitempty"`
+	In               string      `json:"in,omitempty"`
+	Name             string      `json:"name,omitempty"`
+	Flows            *oauthFlows `json:"flows,omitempty"`
+	OpenIDConnectURL string      `json:"openIdConnectUrl,omitempty"`
+}
+
+type oauthFlows struct {
+	Implicit          *oauthFlow `json:"implicit,omitempty"`
+	Password          *oauthFlow `json:"password,omitempty"`
+	ClientCredentials *oauthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *oauthFlow `json:"authorizationCode,omitempty"`
+}
+
+type oauthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty"`
+	RefreshURL       string            `json:"refreshUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []*parameter          `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path|query|header|cookie
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+	Example     any     `json:"example,omitempty"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema  *schema `json:"schema,omitempty"`
+	Example any     `json:"example,omitempty"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Headers     map[string]*header   `json:"headers,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Example              any                `json:"example,omitempty"`
+}
+
+// specBuilder collects one OpenAPI operation per endpoint while Generate runs.
+type specBuilder struct {
+	*schemaGen
+	root    string
+	doc     *openAPIDoc
+	names   map[string]int // method name, and package dir + "." + method name => number of declarations
+	schemes map[string]scanner.SecurityScheme
+	// package dir => exampleKey => //goge:example function
+	examples   map[string]map[string]*bodyExample
+	versions   map[*operation]string // version= of the endpoints that have one
+	successors map[string]string     // see versionSuccessors
+	perVersion bool
+}
+
+func newSpecBuilder(root string, apis map[string]*scanner.PackageAPIs, opts Options) *specBuilder {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: opts.Title, Version: opts.Version},
+		Paths:   map[string]map[string]*operation{},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "API"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+	b := &specBuilder{schemaGen: newSchemaGen("#/components/schemas/"), root: root, doc: doc, names: map[string]int{}, schemes: securitySchemes(apis), examples: map[string]map[string]*bodyExample{}, versions: map[*operation]string{}, successors: versionSuccessors(apis), perVersion: opts.SpecVersions}
+	for _, pkg := range apis {
+		// Validate reported the examples that do not evaluate
+		b.examples[pkg.PkgDir], _ = packageExamples(pkg)
+		for _, ep := range pkg.Endpoints {
+			b.names[ep.MethodName]++
+			b.names[pkg.PkgDir+"."+ep.MethodName]++
+		}
+		// declared schemes are documented even when no endpoint uses them yet
+		for _, s := range pkg.Security {
+			b.addScheme(s)
+		}
+	}
+	return b
+}
+
+func (b *specBuilder) write(out string) error {
+	if defs := b.finish(); defs != nil {
+		if b.doc.Components == nil {
+			b.doc.Components = &components{}
+		}
+		b.doc.Components.Schemas = defs
+	}
+	if err := writeDoc(out, b.doc); err != nil {
+		return err
+	}
+	if !b.perVersion {
+		return nil
+	}
+	for _, version := range b.versionList() {
+		ext := filepath.Ext(out)
+		if err := writeDoc(strings.TrimSuffix(out, ext)+"."+version+ext, b.versionDoc(version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *specBuilder) versionList() []string {
+	var out []string
+	for _, v := range b.versions {
+		out = appendUnique(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return versionLess(out[i], out[j]) })
+	return out
+}
+
+// versionDoc is the document of one version: its operations and the unversioned ones.
+func (b *specBuilder) versionDoc(version string) *openAPIDoc {
+	doc := *b.doc
+	doc.Info.Version = version
+	doc.Paths = map[string]map[string]*operation{}
+	for path, ops := range b.doc.Paths {
+		for method, op := range ops {
+			if v, ok := b.versions[op]; ok && v != version {
+				continue
+			}
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*operation{}
+			}
+			doc.Paths[path][method] = op
+		}
+	}
+	return &doc
+}
+
+func writeDoc(out string, doc *openAPIDoc) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode openapi: %w", err)
+	}
+	if err := os.WriteFile(out, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", out, err)
+	}
+	return nil
+}
+
+func (b *specBuilder) add(pkg *scanner.PackageAPIs, ep scanner.Endpoint, ev endpointVM) {
+	r := parseRoute(ep.HTTPMethod, ep.Path)
+	op := &operation{
+		OperationID: ep.OperationID,
+		Summary:     ep.Summary,
+		Description: ep.Description,
+		Tags:        ep.Tags,
+		Deprecated:  ep.Deprecated,
+		Responses:   map[string]*response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = ep.MethodName
+		if b.names[ep.MethodName] > 1 {
+			op.OperationID = pkg.PkgName + ep.MethodName
+		}
+		if b.names[pkg.PkgDir+"."+ep.MethodName] > 1 {
+			// several services or a service and a function of the package declare it
+			op.OperationID = pkg.PkgName + ep.MethodName
+			if recv := receiverType(ep.RecvName); recv != "" {
+				op.OperationID = pkg.PkgName + exportName(recv) + ep.MethodName
+			}
+		}
+	}
+	if len(op.Tags) == 0 {
+		op.Tags = []string{pkg.PkgName}
+	}
+
+	var input *astStruct
+	binds := ev.Binds
+	if ep.InputIsStruct {
+		input = findStructAST(b.root, pkg, strings.TrimPrefix(ep.InputTypeExpr, "*"))
+		if binds == nil && input != nil {
+			binds = ExtractBindingsRecursive(pkg, input)
+		}
+	}
+	example := b.example(pkg, ep, ep.InputTypeExpr)
+	b.addParameters(op, pkg, ep, r, binds, example)
+
+	method := strings.ToUpper(ep.HTTPMethod)
+	if input != nil && (method == "POST" || method == "PUT" || method == "PATCH") {
+		if s := b.objectSchema(pkg, input); len(s.Properties) > 0 {
+			var body any
+			if example != nil {
+				body = example.value
+			}
+			op.RequestBody = &requestBody{Required: true, Content: contentOf(ep.Produces, b.structSchema(pkg, input), body)}
+		}
+	}
+	b.addResponses(op, pkg, ep)
+	if ep.Auth != "" {
+		scopes := ep.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		op.Security = []map[string][]string{{ep.Auth: scopes}}
+		op.Responses["401"] = &response{Description: http.StatusText(http.StatusUnauthorized)}
+		if len(scopes) > 0 {
+			op.Responses["403"] = &response{Description: http.StatusText(http.StatusForbidden)}
+		}
+		b.addScheme(b.schemes[ep.Auth])
+	}
+
+	path := openAPIPath(r)
+	if b.doc.Paths[path] == nil {
+		b.doc.Paths[path] = map[string]*operation{}
+	}
+	b.doc.Paths[path][strings.ToLower(r.Method)] = op
+	if ep.Version != "" {
+		b.versions[op] = ep.Version
+	}
+}
+
+// addScheme documents s under components.securitySchemes.
+func (b *specBuilder) addScheme(s scanner.SecurityScheme) {
+	if b.doc.Components == nil {
+		b.doc.Components = &components{SecuritySchemes: map[string]*securityScheme{}}
+	}
+	out := &securityScheme{
+		Type:             s.Type,
+		Description:      s.Description,
+		Scheme:           s.Scheme,
+		BearerFormat:     s.BearerFormat,
+		OpenIDConnectURL: s.OpenIDConnectURL,
+	}
+	if s.Type == "apiKey" {
+		out.In, out.Name = s.In, s.Key
+	}
+	if s.Type == "oauth2" {
+		flow := &oauthFlow{AuthorizationURL: s.AuthorizationURL, TokenURL: s.TokenURL, RefreshURL: s.RefreshURL, Scopes: map[string]string{}}
+		for _, sc := range s.Scopes {
+			flow.Scopes[sc] = ""
+		}
+		out.Flows = &oauthFlows{}
+		switch s.Flow {
+		case "implicit":
+			out.Flows.Implicit = flow
+		case "password":
+			out.Flows.Password = flow
+		case "clientCredentials":
+			out.Flows.ClientCredentials = flow
+		default:
+			out.Flows.AuthorizationCode = flow
+		}
+	}
+	b.doc.Components.SecuritySchemes[s.Name] = out
+}
+
+// example returns the //goge:example function of typeExpr, written in the file of ep,
+// or nil.
+func (b *specBuilder) example(pkg *scanner.PackageAPIs, ep scanner.Endpoint, typeExpr string) *bodyExample {
+	if typeExpr == "" {
+		return nil
+	}
+	return b.examples[pkg.PkgDir][exampleKey(packageID(pkg.PkgDir), ep.Imports, typeExpr)]
+}
+
+// addParameters documents the bound fields of the input; example, when the input has
+// one, gives the parameters their example values.
+func (b *specBuilder) addParameters(op *operation, pkg *scanner.PackageAPIs, ep scanner.Endpoint, r route, binds []FieldBind, example *bodyExample) {
+	seen := map[string]bool{}
+	add := func(p *parameter) {
+		if seen[p.In+":"+p.Name] {
+			return
+		}
+		seen[p.In+":"+p.Name] = true
+		op.Parameters = append(op.Parameters, p)
+	}
+
+	for _, bd := range binds {
+		if bd.Kind == "page" || bd.Kind == "cursor" {
+			for _, p := range pageParameters(bd) {
+				add(p)
+			}
+			continue
+		}
+		in := map[string]string{"url": "path", "query": "query", "header": "header", "cookie": "cookie"}[bd.Kind]
+		if in == "" {
+			continue
+		}
+		p := &parameter{Name: wildcardName(bd.Key), In: in, Description: bd.Doc, Required: in == "path", Schema: bindSchema(bd)}
+		p.Example = exampleOf(bd.Example, p.Schema)
+		if example != nil {
+			if v, ok := example.bound[bd.Name]; ok {
+				p.Example = v
+			}
+		}
+		add(p)
+	}
+	if !ep.InputIsStruct && ep.InputTypeExpr != "" {
+		key, _ := primitiveParam(ep.Path)
+		p := &parameter{Name: wildcardName(key), In: "query", Schema: b.exprSchema(pkg, ep.InputTypeExpr)}
+		if len(r.Keys()) > 0 {
+			p.In, p.Required = "path", true
+		}
+		add(p)
+	}
+	// parameters read by manual handlers still belong to the path
+	for _, key := range r.Keys() {
+		add(&parameter{Name: wildcardName(key), In: "path", Required: true, Schema: &schema{Type: "string"}})
+	}
+}
+
+func (b *specBuilder) addResponses(op *operation, pkg *scanner.PackageAPIs, ep scanner.Endpoint) {
+	code := ep.Status
+	if code == 0 {
+		code = http.StatusOK
+	}
+	res := &response{}
+
+	switch {
+	case ep.Stream == scanner.StreamWS:
+		code = http.StatusSwitchingProtocols
+		res.Description = fmt.Sprintf("WebSocket: the client sends %s messages and receives %s replies, JSON encoded", ep.WSIn, ep.WSOut)
+	case ep.Stream == scanner.StreamChan:
+		res.Content = map[string]mediaType{"text/event-stream": {Schema: b.exprSchema(pkg, ep.ReturnTypeExpr)}}
+	case ep.Stream == scanner.StreamSink:
+		res.Content = map[string]mediaType{"text/event-stream": {Schema: &schema{Type: "string"}}}
+	case ep.ReturnTypeExpr == "":
+		if ep.Status == 0 {
+			code = http.StatusNoContent
+		}
+	case ep.ReturnKind != "":
+		ct := ep.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		res.Content = map[string]mediaType{ct: {Schema: &schema{Type: "string", Format: "binary"}}}
+		if ep.ReturnKind == scanner.ReturnFile {
+			res.Headers = map[string]*header{"Content-Disposition": {Schema: &schema{Type: "string"}}}
+		}
+	default:
+		var body any
+		if example := b.example(pkg, ep, ep.ReturnTypeExpr); example != nil {
+			body = example.value
+		}
+		res.Content = contentOf(ep.Produces, b.exprSchema(pkg, ep.ReturnTypeExpr), body)
+		if isNamedType(ep.ReturnTypeExpr) {
+			if st := findStructAST(b.root, pkg, strings.TrimPrefix(ep.ReturnTypeExpr, "*")); st != nil {
+				res.Headers = responseHeaders(ExtractBindingsRecursive(pkg, st))
+			}
+		}
+		if _, ok := pageResultItem(ep); ok {
+			res.Headers = map[string]*header{"Link": {Description: "Links to the first, previous, next and last pages", Schema: &schema{Type: "string"}}}
+		}
+	}
+	if res.Description == "" {
+		res.Description = http.StatusText(code)
+	}
+	if ep.Deprecated {
+		if res.Headers == nil {
+			res.Headers = map[string]*header{}
+		}
+		maps.Copy(res.Headers, deprecationHeaders(ep, b.successors[versionKey(ep)+" "+ep.Version]))
+	}
+	if ep.CacheMaxAge > 0 || ep.ETag {
+		if res.Headers == nil {
+			res.Headers = map[string]*header{}
+		}
+		res.Headers["Cache-Control"] = &header{Schema: &schema{Type: "string", Example: cacheControl(ep)}}
+	}
+	if ep.ETag {
+		res.Headers["ETag"] = &header{Description: "Strong entity tag of the body, for If-None-Match", Schema: &schema{Type: "string"}}
+		op.Parameters = append(op.Parameters, &parameter{Name: "If-None-Match", In: "header", Description: "ETag of the copy the client holds", Schema: &schema{Type: "string"}})
+		op.Responses[strconv.Itoa(http.StatusNotModified)] = &response{Description: "Not Modified: the copy matching If-None-Match is still current"}
+	}
+	op.Responses[strconv.Itoa(code)] = res
+}
+
+// deprecationHeaders documents the headers set by the handler of a deprecated endpoint,
+// see buildDeprecationCode.
+func deprecationHeaders(ep scanner.Endpoint, successor string) map[string]*header {
+	headers := map[string]*header{}
+	if ep.DeprecatedAt != "" {
+		headers["Deprecation"] = &header{Description: "Deprecated endpoint since " + ep.DeprecatedAt, Schema: &schema{Type: "string"}}
+	}
+	if ep.Sunset != "" {
+		headers["Sunset"] = &header{Description: "Removed on " + ep.Sunset, Schema: &schema{Type: "string"}}
+	}
+	var links []string
+	if ep.DeprecationURL != "" {
+		links = append(links, "the migration guide "+ep.DeprecationURL)
+	}
+	if successor != "" {
+		links = append(links, "the "+successor+" of the endpoint")
+	}
+	if len(links) > 0 {
+		headers["Link"] = &header{Description: "Links to " + strings.Join(links, " and "), Schema: &schema{Type: "string"}}
+	}
+	return headers
+}
+
+// responseHeaders documents gogeRespHeader fields and the cookies set by gogeRespCookie.
+func responseHeaders(binds []FieldBind) map[string]*header {
+	headers := map[string]*header{}
+	cookies := []string{}
+	for _, bd := range binds {
+		switch bd.Kind {
+		case "respHeader":
+			headers[bd.Key] = &header{Description: bd.Doc, Schema: &schema{Type: "string"}}
+		case "respCookie":
+			cookies = append(cookies, bd.Key)
+		}
+	}
+	if len(cookies) > 0 {
+		headers["Set-Cookie"] = &header{Description: "Sets the " + strings.Join(cookies, ", ") + " cookie", Schema: &schema{Type: "string"}}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+func contentOf(produces []string, s *schema, example any) map[string]mediaType {
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+	content := map[string]mediaType{}
+	for _, mt := range produces {
+		content[mt] = mediaType{Schema: s, Example: example}
+	}
+	return content
+}
+
+// openAPIPath turns `/users/:id<int>/*` into `/users/{id}/{wildcard}`.
+func openAPIPath(r route) string {
+	var sb strings.Builder
+	for _, s := range r.Segments {
+		sb.WriteByte('/')
+		for _, t := range s {
+			if t.Param == "" {
+				sb.WriteString(t.Text)
+			} else {
+				sb.WriteString("{" + wildcardName(t.Param) + "}")
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "/"
+	}
+	return sb.String()
+}
+
+// bindSchema describes a header, query, path or cookie value.
+func bindSchema(bd FieldBind) *schema {
+	s := &schema{Type: kindSchemaType(bd.KindHint)}
+	if bd.Enum != nil {
+		s.Type = kindSchemaType(bd.Enum.Kind)
+		s.Enum = enumValues(bd.Enum)
+	}
+	if bd.HasDefault {
+		s.Default = jsonValue(defaultLiteral(bd.DefaultValue, s.kind()))
+	}
+	return s
+}
+
+func kindSchemaType(k valKind) string {
+	switch k {
+	case kindInt:
+		return "integer"
+	case kindFloat:
+		return "number"
+	case kindBool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func (s *schema) kind() valKind {
+	switch s.Type {
+	case "integer":
+		return kindInt
+	case "number":
+		return kindFloat
+	case "boolean":
+		return kindBool
+	default:
+		return kindString
+	}
+}
+
+// jsonValue converts a Go literal produced by defaultLiteral or enumType.Literals.
+func jsonValue(lit string) any {
+	if s, err := strconv.Unquote(lit); err == nil {
+		return s
+	}
+	return json.RawMessage(lit)
+}
+
+func enumValues(e *enumType) []any {
+	out := []any{}
+	for _, lit := range e.Literals() {
+		out = append(out, jsonValue(lit))
+	}
+	return out
+}
+
+func hasGogeTag(f *ast.Field) bool {
+	if f.Tag == nil {
+		return false
+	}
+	tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
+	for _, key := range []string{_TAG_HEADER, _TAG_QUERY, _TAG_URL, _TAG_COOKIE, _TAG_RESP_HEADER, _TAG_RESP_COOKIE, _TAG_AUTH} {
+		if _, ok := tag.Lookup(key); ok {
+			return true
+		}
+	}
+	return false
+}
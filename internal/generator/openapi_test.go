@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+func TestOpenAPIPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/":                    "/",
+		"/users/:id<int>":      "/users/{id}",
+		"/files/:name.:ext/*":  "/files/{name}.{ext}/{wildcard}",
+		"/a/:b?/+2":            "/a/{b}/{wildcard2}",
+		`/v1/name\:verb`:       "/v1/name:verb",
+		"/flights/:from-:to/":  "/flights/{from}-{to}",
+		"/Users/ById/:user_id": "/Users/ById/{user_id}",
+	} {
+		if got := openAPIPath(parseRoute("GET", in)); got != want {
+			t.Errorf("openAPIPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBindSchema(t *testing.T) {
+	enum := &enumType{Name: "Level", Kind: kindInt, Values: []enumValue{{"Low", "1"}, {"High", "2"}}}
+	for _, c := range []struct {
+		bind FieldBind
+		want string
+	}{
+		{FieldBind{Kind: "query", KindHint: kindInt, HasDefault: true, DefaultValue: "20"}, `{"type":"integer","default":20}`},
+		{FieldBind{Kind: "query", KindHint: kindBool, HasDefault: true, DefaultValue: "TRUE"}, `{"type":"boolean","default":true}`},
+		{FieldBind{Kind: "cookie", HasDefault: true, DefaultValue: "en"}, `{"type":"string","default":"en"}`},
+		{FieldBind{Kind: "header", Enum: enum, HasDefault: true, DefaultValue: "2"}, `{"type":"integer","enum":[1,2],"default":2}`},
+	} {
+		got, err := json.Marshal(bindSchema(c.bind))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.want {
+			t.Errorf("bindSchema(%+v) = %s, want %s", c.bind, got, c.want)
+		}
+	}
+}
+
+func TestSpecVersions(t *testing.T) {
+	dir := t.TempDir()
+	src := `package svc
+
+type service struct{}
+
+//goge:api method=GET path=/health
+func (s *service) Health() error { return nil }
+
+//goge:api method=GET path=/users version=v1
+func (s *service) ListUsersV1() error { return nil }
+
+//goge:api method=GET path=/users version=v2
+func (s *service) ListUsers() error { return nil }
+`
+	if err := os.WriteFile(filepath.Join(dir, "svc.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	apis, err := scanner.Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Generate(dir, apis, Options{Spec: "openapi.json", SpecVersions: true}); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"openapi.json":    "/health /v1/users /v2/users",
+		"openapi.v1.json": "/health /v1/users",
+		"openapi.v2.json": "/health /v2/users",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc openAPIDoc
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for p := range doc.Paths {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		if got := strings.Join(paths, " "); got != want {
+			t.Errorf("%s: paths %s, want %s", file, got, want)
+		}
+	}
+}
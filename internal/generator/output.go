@@ -0,0 +1,307 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// target is where the generated files of a service package go. By default that is the
+// package itself; with Options.Out it is a separate package that imports it, so the
+// service package does not depend on Fiber.
+type target struct {
+	dir        string          // output directory
+	importPath string          // import path of the service package; empty when generating in place
+	pkgName    string          // name of the service package
+	alias      string          // name the generated files import the service package under
+	decls      map[string]bool // exported top-level names of the service package
+	own        map[string]bool // names declared by the files written so far
+}
+
+// newTarget places the output of the package in pkgDir. Out is relative to root and
+// mirrors the layout under it: with Out "internal/transport/http" the package in
+// <root>/user is written to <root>/internal/transport/http/user. The service package is
+// imported under an alias from imps.
+func newTarget(root, out string, pkg *scanner.PackageAPIs, imps *importSet) (*target, error) {
+	pkgDir := pkg.PkgDir
+	if out == "" {
+		return &target{dir: pkgDir}, nil
+	}
+	rel, err := filepath.Rel(root, pkgDir)
+	if err != nil {
+		return nil, fmt.Errorf("output for %s: %w", pkgDir, err)
+	}
+	ip, err := importPath(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	decls, err := exportedDecls(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	alias := imps.add(ip, pkg.PkgName)
+	return &target{dir: filepath.Join(root, out, rel), importPath: ip, pkgName: pkg.PkgName, alias: alias, decls: decls, own: map[string]bool{}}, nil
+}
+
+// importPath computes the import path of dir from the module declared in the nearest
+// go.mod.
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	modRoot := findModuleRoot(abs)
+	if modRoot == "" {
+		return "", fmt.Errorf("%s: no go.mod found; -out needs a module to compute import paths", dir)
+	}
+	mod, err := modulePath(filepath.Join(modRoot, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(modRoot, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return mod, nil
+	}
+	return mod + "/" + filepath.ToSlash(rel), nil
+}
+
+func modulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no module directive", gomod)
+}
+
+// exportedDecls lists the exported top-level names declared by the package in dir,
+// ignoring tests and generated files.
+func exportedDecls(dir string) (map[string]bool, error) {
+	fset := token.NewFileSet()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	decls := map[string]bool{}
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") || strings.HasSuffix(p, "_gen.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", p, err)
+		}
+		for name := range topLevelNames(f) {
+			if ast.IsExported(name) {
+				decls[name] = true
+			}
+		}
+	}
+	return decls, nil
+}
+
+// topLevelNames returns the package-level types, functions, constants and variables of f.
+func topLevelNames(f *ast.File) map[string]bool {
+	names := map[string]bool{}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				names[d.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names[s.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						names[n.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// write formats src and writes it to name in the target directory, first qualifying the
+// references to the service package when the target is another package and dropping
+// unused imports.
+func (t *target) write(name string, src []byte) error {
+	out := filepath.Join(t.dir, name)
+	var err error
+	if t.importPath != "" {
+		if src, err = t.qualify(src); err != nil {
+			return fmt.Errorf("qualify %s: %w", name, err)
+		}
+		if err := os.MkdirAll(t.dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if src, err = pruneImports(src); err != nil {
+		return fmt.Errorf("imports %s: %w", name, err)
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("format %s: %w", name, err)
+	}
+	if err := os.WriteFile(out, formatted, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", out, err)
+	}
+	return nil
+}
+
+// qualify rewrites identifiers declared by the service package, e.g. GetUserReq to
+// user.GetUserReq, and imports it when anything was rewritten. Field, method and
+// parameter names and the file's own declarations are left alone.
+func (t *target) qualify(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	for name := range topLevelNames(f) {
+		t.own[name] = true
+	}
+	names := map[*ast.Ident]bool{} // identifiers that name something rather than refer to it
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			names[n.Sel] = true
+		case *ast.Field:
+			for _, id := range n.Names {
+				names[id] = true
+			}
+		case *ast.KeyValueExpr:
+			// generated composite literals are structs, keyed by field name
+			if id, ok := n.Key.(*ast.Ident); ok {
+				names[id] = true
+			}
+		case *ast.FuncDecl:
+			names[n.Name] = true
+		case *ast.TypeSpec:
+			names[n.Name] = true
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				names[id] = true
+			}
+		}
+		return true
+	})
+
+	changed := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || names[id] || t.own[id.Name] || !t.decls[id.Name] {
+			return true
+		}
+		id.Name = t.alias + "." + id.Name
+		changed = true
+		return true
+	})
+	if !changed {
+		return src, nil
+	}
+	if scanner.ImportName(t.importPath) == t.alias {
+		astutil.AddImport(fset, f, t.importPath)
+	} else {
+		astutil.AddNamedImport(fset, f, t.alias, t.importPath)
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkOut reports what cannot be referenced from a separate output package: package
+// main, unexported types in signatures and bindings, unexported functions and methods,
+// and manual_func= on methods, whose handler would have to be written in the output package.
+func checkOut(root string, pkg *scanner.PackageAPIs) []error {
+	var errs []error
+	if pkg.PkgName == "main" {
+		return []error{fmt.Errorf("%s: package main cannot be imported; -out needs a library package", pkg.PkgDir)}
+	}
+	for _, ep := range pkg.Endpoints {
+		if ep.Func && !ast.IsExported(ep.MethodName) {
+			errs = append(errs, fmt.Errorf("%s: %s: unexported functions cannot be served from another package", ep.Pos, ep.MethodName))
+		}
+		if !ep.Func && !ast.IsExported(ep.MethodName) {
+			errs = append(errs, fmt.Errorf("%s: %s: unexported methods cannot be implemented from another package", ep.Pos, ep.MethodName))
+		}
+		if !ep.Func && ep.ManualFunc != "" {
+			errs = append(errs, fmt.Errorf("%s: %s: manual_func on a method needs a handler method in the output package; use a function endpoint", ep.Pos, ep.MethodName))
+		}
+		if ep.Func && ep.ManualFunc != "" && !ast.IsExported(ep.ManualFunc) {
+			errs = append(errs, fmt.Errorf("%s: %s: manual_func %s must be exported to be called from another package", ep.Pos, ep.MethodName, ep.ManualFunc))
+		}
+		exprs := []string{ep.InputTypeExpr, ep.ReturnTypeExpr, ep.WSIn, ep.WSOut}
+		if ep.InputIsStruct && ep.ManualFunc == "" {
+			st := findStructAST(root, pkg, strings.TrimPrefix(ep.InputTypeExpr, "*"))
+			for _, b := range ExtractBindingsRecursive(pkg, st) {
+				exprs = append(exprs, b.TypeExpr)
+			}
+		}
+		for _, expr := range exprs {
+			if name := unexportedType(expr); name != "" {
+				errs = append(errs, fmt.Errorf("%s: %s: type %s is unexported and cannot be used from another package", ep.Pos, ep.MethodName, name))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// unexportedType returns the first unqualified, non-predeclared, unexported type name
+// in expr, e.g. "slug" for "[]slug".
+func unexportedType(expr string) string {
+	if expr == "" {
+		return ""
+	}
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return ""
+	}
+	found := ""
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			return false // qualified by another package
+		case *ast.Ident:
+			if found == "" && !ast.IsExported(n.Name) && !predeclared[n.Name] {
+				found = n.Name
+			}
+		}
+		return true
+	})
+	return found
+}
+
+var predeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+}
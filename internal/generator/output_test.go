@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+func TestUnexportedType(t *testing.T) {
+	for expr, want := range map[string]string{
+		"*GetReq":              "",
+		"[]slug":               "slug",
+		"map[string]*dto.item": "",
+		"<-chan event":         "event",
+		"error":                "",
+		"":                     "",
+	} {
+		if got := unexportedType(expr); got != want {
+			t.Errorf("unexportedType(%q) = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestCheckOut(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "catalog")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := `package catalog
+
+type slug string
+
+type Req struct {
+	Kind kind ` + "`gogeQuery:\"kind\"`" + `
+}
+
+type kind string
+
+const book kind = "book"
+
+type service struct{}
+
+//goge:api method=GET path=/tags/:slug
+func (s *service) Tag(t slug) error { return nil }
+
+//goge:api method=GET path=/items
+func (s *service) Items(req *Req) error { return nil }
+
+//goge:api method=GET path=/ping
+func ping() error { return nil }
+
+//goge:api method=GET path=/list
+func (s *service) list() error { return nil }
+
+//goge:api method=GET path=/upload manual_func=Upload
+func (s *service) Upload() error { return nil }
+`
+	if err := os.WriteFile(filepath.Join(dir, "svc.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	apis, err := scanner.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, err := range checkOut(root, apis[dir]) {
+		msgs = append(msgs, err.Error())
+	}
+	msg := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		"Tag: type slug is unexported",
+		"Items: type kind is unexported",
+		"ping: unexported functions cannot be served from another package",
+		"list: unexported methods cannot be implemented from another package",
+		"Upload: manual_func on a method needs a handler method",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("missing %q in:\n%s", want, msg)
+		}
+	}
+
+	if ip, err := importPath(dir); err != nil || ip != "example.com/app/catalog" {
+		t.Fatalf("importPath = %q, %v", ip, err)
+	}
+}
@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// Limits of goge.Page and goge.Cursor when the embedded field has no gogePage tag.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageBind recognizes an embedded goge.Page or goge.Cursor, which bind the ?page= or
+// ?cursor= and ?limit= query values, with the limits of the field's gogePage tag.
+func pageBind(pkg *scanner.PackageAPIs, owner *astStruct, f *ast.Field) (FieldBind, bool) {
+	name, ok := runtimeType(pkg, owner, f.Type)
+	if !ok || (name != "Page" && name != "Cursor") {
+		return FieldBind{}, false
+	}
+	b := FieldBind{Name: name, Kind: strings.ToLower(name), Limit: defaultPageLimit, MaxLimit: maxPageLimit}
+	if f.Tag != nil {
+		if v, ok := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Lookup(_TAG_PAGE); ok {
+			b.Options = strings.Split(v, ",")
+			if limit, max, err := parsePageTag(v); err == nil {
+				b.Limit, b.MaxLimit = limit, max
+			}
+		}
+	}
+	return b, true
+}
+
+// parsePageTag reads `gogePage:"limit=20,max=100"`; either may be left out.
+func parsePageTag(v string) (limit, max int, err error) {
+	limit, max = defaultPageLimit, maxPageLimit
+	for _, opt := range strings.Split(v, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("%s: %q is not a positive integer", key, val)
+		}
+		switch key {
+		case "limit":
+			limit = n
+		case "max":
+			max = n
+		default:
+			return 0, 0, fmt.Errorf("unknown option %q, want limit= or max=", key)
+		}
+	}
+	if limit > max {
+		return 0, 0, fmt.Errorf("limit=%d is above max=%d", limit, max)
+	}
+	return limit, max, nil
+}
+
+// runtimeType returns the name of the goge runtime type expr denotes, e.g. "Page".
+func runtimeType(pkg *scanner.PackageAPIs, owner *astStruct, expr ast.Expr) (string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	if ip, _ := owner.importOf(pkg, x.Name); ip != scanner.RuntimeImportPath {
+		return "", false
+	}
+	return sel.Sel.Name, true
+}
+
+// pageResultItem returns the item type of a goge.PageResult[T] result, written in the
+// file declaring ep.
+func pageResultItem(ep scanner.Endpoint) (ast.Expr, bool) {
+	expr, err := parser.ParseExpr(strings.TrimPrefix(ep.ReturnTypeExpr, "*"))
+	if err != nil {
+		return nil, false
+	}
+	idx, ok := expr.(*ast.IndexExpr)
+	if !ok {
+		return nil, false
+	}
+	sel, ok := idx.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "PageResult" {
+		return nil, false
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || ep.Imports[x.Name] != scanner.RuntimeImportPath {
+		return nil, false
+	}
+	return idx.Index, true
+}
+
+// buildPageLinksCode sets the Link header of a goge.PageResult to the other pages,
+// numbered when the input embeds goge.Page and by cursor otherwise.
+func buildPageLinksCode(binds []FieldBind, inputIsStruct bool) string {
+	call := "res.CursorLinks(c.OriginalURL(), goge.Cursor{})"
+	if inputIsStruct {
+		if b, ok := pageOf(binds); ok && b.Kind == "page" {
+			call = "res.PageLinks(c.OriginalURL(), req." + b.Name + ")"
+		} else if ok {
+			call = "res.CursorLinks(c.OriginalURL(), req." + b.Name + ")"
+		}
+	}
+	return fmt.Sprintf("if links := %s; links != \"\" {\n\tc.Append(fiber.HeaderLink, links)\n}", call)
+}
+
+// pageOf returns the goge.Page or goge.Cursor binding among binds.
+func pageOf(binds []FieldBind) (FieldBind, bool) {
+	for _, b := range binds {
+		if b.Kind == "page" || b.Kind == "cursor" {
+			return b, true
+		}
+	}
+	return FieldBind{}, false
+}
+
+// pageParameters documents the query values a goge.Page or goge.Cursor binds.
+func pageParameters(b FieldBind) []*parameter {
+	one := 1
+	limit := &parameter{Name: "limit", In: "query", Description: "Items per page",
+		Schema: &schema{Type: "integer", Minimum: &one, Maximum: &b.MaxLimit, Default: b.Limit}}
+	if b.Kind == "cursor" {
+		return []*parameter{
+			{Name: "cursor", In: "query", Description: "next_cursor of the previous page; omitted for the first page", Schema: &schema{Type: "string"}},
+			limit,
+		}
+	}
+	return []*parameter{
+		{Name: "page", In: "query", Description: "Page number, from 1", Schema: &schema{Type: "integer", Minimum: &one, Default: 1}},
+		limit,
+	}
+}
+
+// pageResultSchema describes goge.PageResult[T] with items described by item.
+func pageResultSchema(item *schema) *schema {
+	return &schema{
+		Type: "object",
+		Properties: map[string]*schema{
+			"items":       {Type: "array", Items: item},
+			"next_cursor": {Type: "string", Description: "Cursor of the next page, absent on the last one"},
+			"total":       {Type: "integer", Description: "Items over all pages, when known"},
+		},
+		Required: []string{"items"},
+	}
+}
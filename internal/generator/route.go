@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"strings"
+)
+
+// routeToken is a run of literal text or a single parameter inside a route segment.
+type routeToken struct {
+	Text     string // literal text as written (escapes removed)
+	Param    string // parameter name, or "*", "+", "*2", ... for wildcards
+	Rule     string // constraint of the parameter as written, e.g. "int" for `:id<int>`
+	Optional bool   // `:name?`; `*` wildcards may also match nothing
+}
+
+func (t routeToken) isWildcard() bool {
+	return t.Param != "" && (t.Param[0] == '*' || t.Param[0] == '+')
+}
+
+// routeSegment is one `/`-separated part of a Fiber route. Most segments hold a single
+// token; Fiber also allows mixing text and parameters, e.g. `:from-:to` or `:name.:ext`.
+type routeSegment []routeToken
+
+func (s routeSegment) isParam() bool { return len(s) == 1 && s[0].Param != "" && !s[0].isWildcard() }
+
+func (s routeSegment) isWildcard() bool { return len(s) == 1 && s[0].isWildcard() }
+
+// optional reports whether the segment can match an empty path part.
+func (s routeSegment) optional() bool {
+	return len(s) == 1 && (s[0].Optional || strings.HasPrefix(s[0].Param, "*"))
+}
+
+// shape is the segment with parameter names erased and text lower-cased, so segments
+// matching the same requests compare equal.
+func (s routeSegment) shape() string {
+	var sb strings.Builder
+	for _, t := range s {
+		switch {
+		case t.isWildcard():
+			sb.WriteByte(t.Param[0])
+		case t.Param != "":
+			sb.WriteByte(':')
+			if t.Optional {
+				sb.WriteByte('?')
+			}
+		default:
+			sb.WriteString(strings.ToLower(strings.ReplaceAll(t.Text, ":", `\:`)))
+		}
+	}
+	return sb.String()
+}
+
+type route struct {
+	Method   string // upper-case HTTP verb as registered (WS endpoints register GET)
+	Path     string
+	Segments []routeSegment
+}
+
+// parseRoute understands Fiber's route syntax: `:name`, optional `:name?`, constraints
+// `:id<int>`, greedy `*` and `+` wildcards (optionally numbered, `*1`), parameters mixed
+// with text inside a segment and `\:` escapes.
+func parseRoute(method, path string) route {
+	r := route{Method: strings.ToUpper(method), Path: path}
+	if r.Method == "WS" {
+		r.Method = "GET"
+	}
+	// Fiber ignores a trailing slash unless StrictRouting is enabled.
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if part != "" {
+			r.Segments = append(r.Segments, parseSegment(part))
+		}
+	}
+	return r
+}
+
+func parseSegment(part string) routeSegment {
+	seg := routeSegment{}
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			seg = append(seg, routeToken{Text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(part); i++ {
+		switch ch := part[i]; {
+		case ch == '\\' && i+1 < len(part):
+			i++
+			text.WriteByte(part[i])
+		case ch == ':' && i+1 < len(part) && isParamChar(part[i+1]):
+			flush()
+			j := i + 1
+			for j < len(part) && isParamChar(part[j]) {
+				j++
+			}
+			tok := routeToken{Param: part[i+1 : j]}
+			if j < len(part) && part[j] == '<' {
+				if k := strings.IndexByte(part[j:], '>'); k >= 0 {
+					tok.Rule = part[j+1 : j+k]
+					j += k + 1
+				}
+			}
+			if j < len(part) && part[j] == '?' {
+				tok.Optional = true
+				j++
+			}
+			seg = append(seg, tok)
+			i = j - 1
+		case ch == '*' || ch == '+':
+			flush()
+			j := i + 1
+			for j < len(part) && part[j] >= '0' && part[j] <= '9' {
+				j++
+			}
+			seg = append(seg, routeToken{Param: part[i:j]})
+			i = j - 1
+		default:
+			text.WriteByte(ch)
+		}
+	}
+	flush()
+	return seg
+}
+
+func isParamChar(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// Params returns the named path parameters in order, wildcards excluded.
+func (r route) Params() []string {
+	out := []string{}
+	for _, s := range r.Segments {
+		for _, t := range s {
+			if t.Param != "" && !t.isWildcard() {
+				out = append(out, t.Param)
+			}
+		}
+	}
+	return out
+}
+
+// wildcardName turns a wildcard key ("*", "+2") into an identifier ("wildcard", "wildcard2").
+func wildcardName(key string) string {
+	if key != "" && (key[0] == '*' || key[0] == '+') {
+		return "wildcard" + key[1:]
+	}
+	return key
+}
+
+// Keys returns every c.Params key of the route, wildcards ("*", "+1", ...) included.
+func (r route) Keys() []string {
+	out := []string{}
+	for _, s := range r.Segments {
+		for _, t := range s {
+			if t.Param != "" {
+				out = append(out, t.Param)
+			}
+		}
+	}
+	return out
+}
+
+// Build renders a request path, taking parameter values from values and using
+// fallback for the others.
+func (r route) Build(values map[string]string, fallback string) string {
+	var sb strings.Builder
+	for _, s := range r.Segments {
+		sb.WriteByte('/')
+		for _, t := range s {
+			if t.Param == "" {
+				sb.WriteString(t.Text)
+			} else if v, ok := values[t.Param]; ok {
+				sb.WriteString(v)
+			} else {
+				sb.WriteString(fallback)
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "/"
+	}
+	return sb.String()
+}
+
+// Covers reports whether every request matched by o is also matched by r, i.e. r
+// shadows o when it is registered first.
+func (r route) Covers(o route) bool {
+	return r.Method == o.Method && segmentsCover(r.Segments, o.Segments)
+}
+
+func segmentsCover(a, b []routeSegment) bool {
+	if len(a) == 0 {
+		return len(b) == 0
+	}
+	s := a[0]
+	switch {
+	case s.isWildcard():
+		// only a trailing wildcard is understood; anything else is assumed not to overlap
+		if len(a) > 1 {
+			return false
+		}
+		if s[0].Param[0] == '+' {
+			return len(b) > 0 && !b[0].optional()
+		}
+		return true
+	case s.isParam():
+		if s[0].Optional && segmentsCover(a[1:], b) {
+			return true
+		}
+		if len(b) == 0 || b[0].isWildcard() || (b[0].optional() && !s[0].Optional) {
+			return false
+		}
+		if s[0].Rule != "" && (!b[0].isParam() || b[0][0].Rule != s[0].Rule) {
+			// a constraint rejects some values: it only covers a parameter with the same one
+			return false
+		}
+		return segmentsCover(a[1:], b[1:])
+	default:
+		// static text and mixed segments only cover segments of the same shape
+		if len(b) == 0 || b[0].shape() != s.shape() {
+			return false
+		}
+		return segmentsCover(a[1:], b[1:])
+	}
+}
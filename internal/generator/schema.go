@@ -0,0 +1,465 @@
+package generator
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// schemaGen describes Go types as JSON Schema, in the dialect of OpenAPI 3.0. Named
+// structs are described once and referred to with $ref, which also ends the recursion of
+// recursive types; finish names the definitions.
+type schemaGen struct {
+	refPrefix string             // "#/components/schemas/" or "#/$defs/"
+	defs      map[string]*schema // struct key => definition
+	structs   map[string]*astStruct
+	refs      []*schema // schemas whose Ref holds a struct key until finish
+}
+
+func newSchemaGen(refPrefix string) *schemaGen {
+	return &schemaGen{refPrefix: refPrefix, defs: map[string]*schema{}, structs: map[string]*astStruct{}}
+}
+
+// exprSchema describes a type written in a method signature of pkg.
+func (g *schemaGen) exprSchema(pkg *scanner.PackageAPIs, typeExpr string) *schema {
+	expr, err := parser.ParseExpr(typeExpr)
+	if err != nil {
+		return &schema{}
+	}
+	return g.typeSchema(pkg, nil, expr)
+}
+
+// typeSchema describes expr as used in owner, a struct of the package, or of an import
+// when owner was loaded from one. A nil owner means the service package itself.
+func (g *schemaGen) typeSchema(pkg *scanner.PackageAPIs, owner *astStruct, expr ast.Expr) *schema {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.typeSchema(pkg, owner, t.X)
+	case *ast.ChanType:
+		return g.typeSchema(pkg, owner, t.Value)
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && id.Name == "byte" {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: g.typeSchema(pkg, owner, t.Elt)}
+	case *ast.MapType:
+		return &schema{Type: "object", AdditionalProperties: g.typeSchema(pkg, owner, t.Value)}
+	case *ast.Ident:
+		if s := basicSchema(t.Name); s != nil {
+			return s
+		}
+		if owner == nil {
+			if e := parseEnumAST(pkg.PkgDir, t.Name); e != nil {
+				return &schema{Type: kindSchemaType(e.Kind), Enum: enumValues(e)}
+			}
+			if st := parseStructAST(pkg.PkgDir, t.Name); st != nil {
+				return g.structSchema(pkg, st)
+			}
+			return g.underlyingSchema(pkg, owner, pkg.PkgDir, t.Name)
+		}
+		if e, _, _ := findEnum(pkg, owner, t); e != nil {
+			return &schema{Type: kindSchemaType(e.Kind), Enum: enumValues(e)}
+		}
+		if st := owner.load(t.Name); st != nil {
+			return g.structSchema(pkg, st)
+		}
+		if owner.ImportPath == "" {
+			return g.underlyingSchema(pkg, owner, cmp.Or(owner.pkgDir, pkg.PkgDir), t.Name)
+		}
+	case *ast.IndexExpr:
+		if name, ok := runtimeType(pkg, owner, t.X); ok && name == "PageResult" {
+			return pageResultSchema(g.typeSchema(pkg, owner, t.Index))
+		}
+	case *ast.SelectorExpr:
+		x, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		ip, _ := owner.importOf(pkg, x.Name)
+		switch ip + "." + t.Sel.Name {
+		case "time.Time":
+			return &schema{Type: "string", Format: "date-time"}
+		case "time.Duration":
+			return &schema{Type: "integer", Format: "int64"}
+		}
+		if e, _, _ := findEnum(pkg, owner, t); e != nil {
+			return &schema{Type: kindSchemaType(e.Kind), Enum: enumValues(e)}
+		}
+		if st := resolveEmbeddedStruct(pkg, owner, t); st != nil {
+			return g.structSchema(pkg, st)
+		}
+	}
+	return &schema{}
+}
+
+// underlyingSchema describes a type of the package in dir that is neither an enum nor a
+// struct, e.g. `type Tags []string`, by its underlying type. It needs type information;
+// without it, or for types encoding themselves with MarshalJSON, the schema is empty.
+func (g *schemaGen) underlyingSchema(pkg *scanner.PackageAPIs, owner *astStruct, dir, name string) *schema {
+	switch dirMarshalers(dir)[name] {
+	case "json":
+		return &schema{}
+	case "text":
+		return &schema{Type: "string"}
+	}
+	ti := loadTypes(dir)
+	if ti == nil {
+		return &schema{}
+	}
+	tn, ok := ti.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return &schema{}
+	}
+	if _, ok := tn.Type().Underlying().(*types.Struct); ok {
+		return &schema{}
+	}
+	expr, err := parser.ParseExpr(types.TypeString(tn.Type().Underlying(), func(p *types.Package) string {
+		if p == ti.pkg {
+			return ""
+		}
+		return p.Name()
+	}))
+	if err != nil {
+		return &schema{}
+	}
+	return g.typeSchema(pkg, owner, expr)
+}
+
+func basicSchema(name string) *schema {
+	switch name {
+	case "string":
+		return &schema{Type: "string"}
+	case "bool":
+		return &schema{Type: "boolean"}
+	case "int", "int8", "int16", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune":
+		return &schema{Type: "integer"}
+	case "int32":
+		return &schema{Type: "integer", Format: "int32"}
+	case "int64":
+		return &schema{Type: "integer", Format: "int64"}
+	case "float32":
+		return &schema{Type: "number", Format: "float"}
+	case "float64":
+		return &schema{Type: "number", Format: "double"}
+	case "any", "error":
+		return &schema{}
+	}
+	return nil
+}
+
+// structSchema refers to the definition of st, adding it on first use. Structs with
+// their own MarshalJSON may encode to anything and are not described; MarshalText
+// makes them strings.
+func (g *schemaGen) structSchema(pkg *scanner.PackageAPIs, st *astStruct) *schema {
+	switch st.marshaler() {
+	case "json":
+		return &schema{}
+	case "text":
+		return &schema{Type: "string"}
+	}
+	key := st.key()
+	if _, ok := g.defs[key]; !ok {
+		g.defs[key] = nil // a recursive field refers to the definition being built
+		g.structs[key] = st
+		g.defs[key] = g.objectSchema(pkg, st)
+	}
+	ref := &schema{Ref: key}
+	g.refs = append(g.refs, ref)
+	return ref
+}
+
+// objectSchema describes the JSON body of a struct the way encoding/json writes it:
+// exported fields by their json name, required unless omitempty, embedded structs
+// flattened, fields bound by goge tags left out.
+func (g *schemaGen) objectSchema(pkg *scanner.PackageAPIs, st *astStruct) *schema {
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	for _, f := range st.Fields() {
+		if len(f.Names) == 0 {
+			if parseJSONTag(f).name == "" {
+				if emb := resolveEmbeddedStruct(pkg, st, f.Type); emb != nil {
+					es := g.objectSchema(pkg, emb)
+					for name, prop := range es.Properties {
+						s.Properties[name] = prop
+					}
+					s.Required = append(s.Required, es.Required...)
+					continue
+				}
+			}
+			// an embedded type with a json name, or not a struct, is a field
+			g.addProperty(pkg, st, s, f, embeddedName(f.Type))
+			continue
+		}
+		g.addProperty(pkg, st, s, f, f.Names[0].Name)
+	}
+	sort.Strings(s.Required)
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	return s
+}
+
+func (g *schemaGen) addProperty(pkg *scanner.PackageAPIs, st *astStruct, s *schema, f *ast.Field, goName string) {
+	tag := parseJSONTag(f)
+	if !ast.IsExported(goName) || hasGogeTag(f) || tag.skip {
+		return
+	}
+	name := goName
+	if tag.name != "" {
+		name = tag.name
+	}
+	prop := g.typeSchema(pkg, st, f.Type)
+	if tag.asString && (prop.Type == "integer" || prop.Type == "number" || prop.Type == "boolean") {
+		prop.Type = "string"
+	}
+	if doc, example := fieldDoc(f), exampleOf(tagExample(f), prop); doc != "" || example != nil {
+		if prop.Ref != "" {
+			// keywords next to $ref are ignored by OpenAPI 3.0
+			prop = &schema{AllOf: []*schema{prop}}
+		}
+		prop.Description, prop.Example = doc, example
+	}
+	s.Properties[name] = prop
+	if !tag.omitEmpty {
+		s.Required = append(s.Required, name)
+	}
+}
+
+// jsonField is the json tag of a struct field.
+type jsonField struct {
+	name      string // empty when the tag does not rename the field
+	skip      bool   // json:"-"
+	omitEmpty bool   // omitempty or omitzero
+	asString  bool   // the string option: numbers and booleans are quoted
+}
+
+func parseJSONTag(f *ast.Field) jsonField {
+	if f.Tag == nil {
+		return jsonField{}
+	}
+	v, ok := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Lookup("json")
+	if !ok {
+		return jsonField{}
+	}
+	if v == "-" {
+		return jsonField{skip: true}
+	}
+	name, opts, _ := strings.Cut(v, ",")
+	out := jsonField{name: name}
+	for _, o := range strings.Split(opts, ",") {
+		switch o {
+		case "omitempty", "omitzero":
+			out.omitEmpty = true
+		case "string":
+			out.asString = true
+		}
+	}
+	return out
+}
+
+// finish names the definitions after their types and points the references at them. A
+// name declared by several packages is prefixed with the package name.
+func (g *schemaGen) finish() map[string]*schema {
+	if len(g.defs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(g.defs))
+	byName := map[string]int{}
+	for key := range g.defs {
+		keys = append(keys, key)
+		byName[g.structs[key].Name]++
+	}
+	sort.Strings(keys)
+
+	names := map[string]string{}
+	taken := map[string]bool{}
+	for _, key := range keys {
+		st := g.structs[key]
+		name := st.Name
+		if byName[name] > 1 {
+			name = exportName(st.pkgName()) + name
+		}
+		for i := 2; taken[name]; i++ {
+			name = exportName(st.pkgName()) + st.Name + strconv.Itoa(i)
+		}
+		taken[name] = true
+		names[key] = name
+	}
+
+	out := map[string]*schema{}
+	for key, s := range g.defs {
+		out[names[key]] = s
+	}
+	for _, ref := range g.refs {
+		if name, ok := names[ref.Ref]; ok {
+			ref.Ref = g.refPrefix + name
+		}
+	}
+	g.refs = nil
+	return out
+}
+
+// pkgName is the name of the package declaring the struct, taken from its path.
+func (a *astStruct) pkgName() string {
+	if a.ImportPath != "" {
+		return scanner.ImportName(a.ImportPath)
+	}
+	return scanner.ImportName(filepath.ToSlash(packageID(a.pkgDir)))
+}
+
+// marshaler tells how the struct encodes itself: "json" for a MarshalJSON method, "text"
+// for MarshalText, "" when encoding/json walks its fields.
+func (a *astStruct) marshaler() string {
+	if a.ImportPath != "" {
+		externalStructCache.RLock()
+		defer externalStructCache.RUnlock()
+		return externalStructCache.marshalers[a.ImportPath][a.Name]
+	}
+	return dirMarshalers(a.pkgDir)[a.Name]
+}
+
+var marshalerCache = struct {
+	sync.Mutex
+	dirs map[string]map[string]string
+}{dirs: map[string]map[string]string{}}
+
+// dirMarshalers lists the marshalers of the package in dir once.
+func dirMarshalers(dir string) map[string]string {
+	marshalerCache.Lock()
+	defer marshalerCache.Unlock()
+	if m, ok := marshalerCache.dirs[dir]; ok {
+		return m
+	}
+	fset := token.NewFileSet()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var files []*ast.File
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+		if f, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution); err == nil {
+			files = append(files, f)
+		}
+	}
+	m := marshalers(files)
+	marshalerCache.dirs[dir] = m
+	return m
+}
+
+// marshalers maps the types of files with a MarshalJSON or MarshalText method to "json"
+// or "text".
+func marshalers(files []*ast.File) map[string]string {
+	out := map[string]string{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 {
+				continue
+			}
+			recv := embeddedName(fn.Recv.List[0].Type)
+			switch fn.Name.Name {
+			case "MarshalJSON":
+				out[recv] = "json"
+			case "MarshalText":
+				if out[recv] == "" {
+					out[recv] = "text"
+				}
+			}
+		}
+	}
+	return out
+}
+
+// jsonSchemaDoc is a JSON Schema (draft 2020-12) document.
+type jsonSchemaDoc struct {
+	Schema string             `json:"$schema"`
+	Ref    string             `json:"$ref,omitempty"`
+	Defs   map[string]*schema `json:"$defs"`
+}
+
+// JSONSchema describes structs of the package in dir as a JSON Schema document with one
+// definition per struct under $defs, for types exchanged outside HTTP such as events.
+// Without names every exported struct is described; with a single name the document
+// validates that type.
+func JSONSchema(dir string, names []string) ([]byte, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = exportedStructs(dir); err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s: no exported structs", dir)
+		}
+	}
+	pkg := &scanner.PackageAPIs{PkgDir: dir, Imports: map[string]string{}}
+	g := newSchemaGen("#/$defs/")
+	var root *schema
+	for _, name := range names {
+		st := parseStructAST(dir, name)
+		if st == nil {
+			return nil, fmt.Errorf("%s: no struct %s", dir, name)
+		}
+		root = g.structSchema(pkg, st)
+	}
+	doc := jsonSchemaDoc{Schema: "https://json-schema.org/draft/2020-12/schema", Defs: g.finish()}
+	if len(names) == 1 {
+		doc.Ref = root.Ref
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// exportedStructs lists the exported structs of the package in dir, generated files
+// and tests aside.
+func exportedStructs(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	var names []string
+	fset := token.NewFileSet()
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") || strings.HasSuffix(p, "_gen.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.IsExported() && ts.TypeParams == nil {
+					if _, ok := ts.Type.(*ast.StructType); ok {
+						names = append(names, ts.Name.Name)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
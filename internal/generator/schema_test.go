@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	src := "package events\n\n" +
+		"type OrderPlaced struct {\n" +
+		"\tID    string   `json:\"id\"`\n" +
+		"\tLines []Line  `json:\"lines\"`\n" +
+		"\tNote  string   `json:\"note,omitempty\"`\n" +
+		"}\n\n" +
+		"type Line struct {\n" +
+		"\tSKU string `json:\"sku\"`\n" +
+		"\tQty int    `json:\"qty,string\"`\n" +
+		"}\n\n" +
+		"type internal struct{}\n"
+	if err := os.WriteFile(filepath.Join(dir, "events.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := JSONSchema(dir, []string{"OrderPlaced"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc jsonSchemaDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Ref != "#/$defs/OrderPlaced" || len(doc.Defs) != 2 {
+		t.Fatalf("schema:\n%s", data)
+	}
+	order := doc.Defs["OrderPlaced"]
+	if items := order.Properties["lines"].Items; items == nil || items.Ref != "#/$defs/Line" {
+		t.Errorf("lines: %+v", order.Properties["lines"])
+	}
+	if len(order.Required) != 2 || order.Required[0] != "id" || order.Required[1] != "lines" {
+		t.Errorf("required: %v", order.Required)
+	}
+	if qty := doc.Defs["Line"].Properties["qty"]; qty.Type != "string" {
+		t.Errorf("qty: %+v", qty)
+	}
+
+	data, err = JSONSchema(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = jsonSchemaDoc{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Ref != "" || len(doc.Defs) != 2 {
+		t.Fatalf("all structs:\n%s", data)
+	}
+	if _, err := JSONSchema(dir, []string{"Missing"}); err == nil {
+		t.Fatal("expected an error for a missing struct")
+	}
+}
+
+func TestSchemaNames(t *testing.T) {
+	g := newSchemaGen("#/components/schemas/")
+	for _, st := range []*astStruct{
+		{Name: "User", ImportPath: "example.com/app/dto"},
+		{Name: "User", ImportPath: "example.com/legacy/dto"},
+		{Name: "User", ImportPath: "example.com/app/admin"},
+		{Name: "Team", ImportPath: "example.com/app/dto"},
+	} {
+		g.defs[st.key()], g.structs[st.key()] = &schema{}, st
+		g.refs = append(g.refs, &schema{Ref: st.key()})
+	}
+	refs := g.refs
+	defs := g.finish()
+	want := []string{"DtoUser", "DtoUser2", "AdminUser", "Team"}
+	for i, ref := range refs {
+		if ref.Ref != "#/components/schemas/"+want[i] {
+			t.Errorf("ref %d = %s, want %s", i, ref.Ref, want[i])
+		}
+		if defs[want[i]] == nil {
+			t.Errorf("no definition %s", want[i])
+		}
+	}
+}
+
+func TestJSONSchemaNamedTypes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/ev\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := "package ev\n\n" +
+		"type ID string\n\n" +
+		"type Tags []string\n\n" +
+		"type Children map[string]*Node\n\n" +
+		"type Node struct {\n" +
+		"\tID       ID       `json:\"id\"`\n" +
+		"\tTags     Tags     `json:\"tags\"`\n" +
+		"\tChildren Children `json:\"children\"`\n" +
+		"}\n"
+	if err := os.WriteFile(filepath.Join(dir, "ev.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := JSONSchema(dir, []string{"Node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc jsonSchemaDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	node := doc.Defs["Node"]
+	if node == nil {
+		t.Fatalf("schema:\n%s", data)
+	}
+	if id := node.Properties["id"]; id.Type != "string" {
+		t.Errorf("id: %+v", id)
+	}
+	if tags := node.Properties["tags"]; tags.Type != "array" || tags.Items == nil || tags.Items.Type != "string" {
+		t.Errorf("tags: %+v", tags)
+	}
+	if children := node.Properties["children"]; children.Type != "object" || children.AdditionalProperties == nil ||
+		children.AdditionalProperties.Ref != "#/$defs/Node" {
+		t.Errorf("children: %+v", children)
+	}
+}
@@ -0,0 +1,52 @@
+package accounts
+
+import "context"
+
+//goge:security apiKey type=apiKey in=header name=X-API-Key description=Key issued per client
+//goge:security oauth type=oauth2 flow=authorizationCode authorizationUrl=https://auth.example.com/authorize tokenUrl=https://auth.example.com/token scopes=read,write
+
+type Account struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner"`
+}
+
+type User struct {
+	Name string
+}
+
+type GetAccountReq struct {
+	ID string `gogeUrl:"id"`
+	// Caller is the authenticated user.
+	Caller *User `gogeAuth:""`
+}
+
+type ListAccountsReq struct {
+	Owner string `gogeQuery:"owner"`
+	Key   string `gogeHeader:"X-API-Key"`
+}
+
+type UpdateAccountReq struct {
+	ID        string `gogeUrl:"id"`
+	Owner     string `json:"owner"`
+	Principal any    `gogeAuth:""`
+}
+
+type service struct{}
+
+//goge:api method=GET path=/accounts auth=apiKey
+func (s *service) ListAccounts(ctx context.Context, req *ListAccountsReq) ([]Account, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/accounts/:id auth=bearer produces=json,xml
+func (s *service) GetAccount(ctx context.Context, req *GetAccountReq) (*Account, error) {
+	return nil, nil
+}
+
+//goge:api method=PUT path=/accounts/:id auth=oauth scopes=write
+func (s *service) UpdateAccount(ctx context.Context, req *UpdateAccountReq) (*Account, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/health
+func (s *service) Health() error { return nil }
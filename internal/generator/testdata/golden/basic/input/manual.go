@@ -0,0 +1,5 @@
+package users
+
+import "github.com/gofiber/fiber/v2"
+
+func (h *Handler) serveRawUser(c *fiber.Ctx) error { return c.SendString(c.Params("id")) }
@@ -0,0 +1,75 @@
+package users
+
+import "context"
+
+type Status string
+
+const (
+	Active   Status = "active"
+	Inactive Status = "inactive"
+)
+
+type Paging struct {
+	Page  int `gogeQuery:"page,default=1"`
+	Limit int `gogeQuery:"limit,default=20"`
+}
+
+type ListUsersReq struct {
+	Paging
+	Status Status `gogeQuery:"status"`
+	// Token is the caller's bearer token.
+	Token string `gogeHeader:"Authorization"`
+	Lang  string `gogeCookie:"lang,default=en"`
+}
+
+type GetUserReq struct {
+	ID string `gogeUrl:"id"`
+}
+
+type CreateUserReq struct {
+	Name   string `json:"name"` // display name
+	Status Status `json:"status"`
+}
+
+type User struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+}
+
+type CreatedUser struct {
+	User
+	Location string `json:"-" gogeRespHeader:"Location"` // URL of the new user
+	Session  string `json:"-" gogeRespCookie:"session,httpOnly,path=/"`
+	Quota    int    `json:"-" gogeRespHeader:"X-Quota"`
+	Retry    *int64 `json:"-" gogeRespHeader:"Retry-After"`
+}
+
+type service struct{}
+
+//goge:api method=GET path=/health
+func (s *service) Health() error { return nil }
+
+// ListUsers returns one page of users. Filter by status
+// to hide inactive accounts.
+//
+//goge:api method=GET path=/users tags=users,admin operationId=listUsers
+func (s *service) ListUsers(ctx context.Context, req *ListUsersReq) ([]User, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/users/:id
+func (s *service) GetUser(ctx context.Context, req GetUserReq) (*User, error) { return nil, nil }
+
+//goge:api method=POST path=/users status=201
+func (s *service) CreateUser(ctx context.Context, req *CreateUserReq) (*CreatedUser, error) {
+	return nil, nil
+}
+
+// DeleteUser removes a user.
+//
+//goge:api method=DELETE path=/users/:id deprecated
+func (s *service) DeleteUser(req *GetUserReq) error { return nil }
+
+//goge:api method=GET path=/users/:id/raw manual_func=serveRawUser
+func (s *service) RawUser(req *GetUserReq) (*User, error) { return nil, nil }
@@ -0,0 +1,34 @@
+package cache
+
+import "context"
+
+type service struct{}
+
+type Product struct {
+	ID    string `json:"id"`
+	Price int    `json:"price"`
+
+	Revision string `json:"-" gogeRespHeader:"X-Revision"`
+	Region   string `json:"-" gogeRespCookie:"region,path=/"`
+}
+
+type GetReq struct {
+	ID       string `gogeUrl:"id"`
+	Currency string `gogeQuery:"currency,default=EUR"`
+	Lang     string `gogeHeader:"Accept-Language"`
+}
+
+//goge:api method=GET path=/products/:id cache=5m etag
+func (s *service) GetProduct(ctx context.Context, req *GetReq) (*Product, error) {
+	return &Product{ID: req.ID}, nil
+}
+
+//goge:api method=GET path=/products/:id/image etag contentType=image/png
+func (s *service) Image(req *GetReq) ([]byte, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/stats cache=30s produces=json,xml
+func (s *service) Stats() (map[string]int, error) {
+	return nil, nil
+}
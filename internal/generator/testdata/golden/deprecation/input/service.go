@@ -0,0 +1,39 @@
+package deprecation
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type service struct{}
+
+type Order struct {
+	ID string `json:"id"`
+}
+
+type OrderV2 struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type GetReq struct {
+	ID string `gogeUrl:"id"`
+}
+
+// GetOrder is replaced by GetOrderV2.
+//
+//goge:api method=GET path=/orders/:id version=v1 deprecated=2026-06-01 sunset=2027-01-01 deprecationLink=https://example.com/docs/orders-v2
+func (s *service) GetOrder(ctx context.Context, req *GetReq) (*Order, error) {
+	return &Order{ID: req.ID}, nil
+}
+
+//goge:api method=GET path=/orders/:id version=v2
+func (s *service) GetOrderV2(ctx context.Context, req *GetReq) (*OrderV2, error) {
+	return &OrderV2{ID: req.ID}, nil
+}
+
+//goge:api method=POST path=/orders/import manual_func=importOrders deprecated
+func (s *service) Import() error { return nil }
+
+func (h *Handler) importOrders(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusAccepted) }
@@ -0,0 +1,32 @@
+package alerts
+
+type service struct{}
+
+type Level int
+
+const (
+	LevelLow Level = iota + 1
+	LevelMid
+	LevelHigh
+)
+
+type Color string
+
+const (
+	ColorRed   Color = "red"
+	ColorGreen Color = "green"
+)
+
+type Alert struct {
+	ID    string `json:"id"`
+	Level Level  `json:"level"`
+}
+
+type ListReq struct {
+	Level Level `gogeHeader:"X-Level,default=2"`
+	Min   Level `gogeQuery:"min"`
+	Color Color `gogeCookie:"color,default=red"`
+}
+
+//goge:api method=GET path=/alerts
+func (s *service) List(req *ListReq) ([]Alert, error) { return nil, nil }
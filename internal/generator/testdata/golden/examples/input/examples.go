@@ -0,0 +1,24 @@
+package examples
+
+//goge:example
+func exampleCreateUser() *CreateUser {
+	return &CreateUser{Org: "acme", Name: "Grace Hopper", Age: 85, Tags: []string{"navy"}}
+}
+
+//goge:example
+func exampleUser() *User {
+	return &User{
+		Audit:    Audit{CreatedBy: "root"},
+		ID:       7,
+		Name:     "Ada",
+		Status:   StatusActive,
+		Scores:   map[string]float64{"math": 9.5},
+		Password: "hidden",
+		Location: "/users/7",
+	}
+}
+
+//goge:example
+func exampleUsers() []User {
+	return []User{{ID: 1, Name: "Ada", Status: Status("banned")}, {ID: -2, Name: "Alan"}}
+}
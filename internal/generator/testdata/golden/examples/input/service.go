@@ -0,0 +1,44 @@
+package examples
+
+import "context"
+
+type service struct{}
+
+type Status string
+
+const (
+	StatusActive Status = "active"
+	StatusBanned Status = "banned"
+)
+
+type Audit struct {
+	CreatedBy string `json:"createdBy" example:"admin"`
+}
+
+type CreateUser struct {
+	Org    string   `gogeUrl:"org"`
+	DryRun bool     `gogeQuery:"dry_run" example:"true"`
+	Name   string   `json:"name" example:"Ada Lovelace"`
+	Age    int      `json:"age" gogeExample:"36"`
+	Tags   []string `json:"tags" example:"admin,ops"`
+}
+
+type User struct {
+	Audit
+	ID       int64              `json:"id"`
+	Name     string             `json:"name"`
+	Status   Status             `json:"status"`
+	Scores   map[string]float64 `json:"scores,omitempty"`
+	Password string             `json:"-"`
+	Location string             `gogeRespHeader:"Location"`
+}
+
+//goge:api method=POST path=/orgs/:org/users status=201
+func (s *service) CreateUser(ctx context.Context, req *CreateUser) (*User, error) {
+	return &User{Name: req.Name, Status: StatusActive}, nil
+}
+
+//goge:api method=GET path=/users
+func (s *service) ListUsers(ctx context.Context) ([]User, error) {
+	return nil, nil
+}
@@ -0,0 +1,43 @@
+package ping
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PingParams struct {
+	ID    string `gogeUrl:"id"`
+	Query string `gogeQuery:"filter"`
+	Page  int    `gogeQuery:"page,default=1"`
+	Name  string `json:"name"`
+}
+
+type PingResp struct {
+	ID string `json:"id"`
+}
+
+// Ping echoes the id.
+//
+//goge:api method=POST path=/ping/:id
+func Ping(c *fiber.Ctx, params *PingParams) (*PingResp, error) {
+	return &PingResp{ID: params.ID}, nil
+}
+
+//goge:api method=GET path=/pong/:id
+func Pong(ctx context.Context, params *PingParams) (*PingResp, error) {
+	return &PingResp{ID: params.ID}, ctx.Err()
+}
+
+//goge:api method=GET path=/version
+func Version() (string, error) { return "1", nil }
+
+//goge:api method=GET path=/raw manual_func=serveRaw
+func Raw() error { return nil }
+
+func serveRaw(c *fiber.Ctx) error { return c.SendString("raw") }
+
+type service struct{}
+
+//goge:api method=GET path=/status
+func (s *service) Status(ctx context.Context) (string, error) { return "ok", nil }
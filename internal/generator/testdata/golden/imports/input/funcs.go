@@ -0,0 +1,11 @@
+package imports
+
+import (
+	"context"
+	"net/url"
+)
+
+//goge:api method=GET path=/parse
+func Parse(ctx context.Context, raw string) (*url.URL, error) {
+	return url.Parse(raw)
+}
@@ -0,0 +1,11 @@
+package imports
+
+import (
+	"context"
+	"html/template"
+)
+
+//goge:api method=GET path=/html
+func (s *service) HTML(ctx context.Context) (*template.Template, error) {
+	return template.New("html"), nil
+}
@@ -0,0 +1,27 @@
+package imports
+
+import (
+	"context"
+	"net/netip"
+	"text/template"
+)
+
+type service struct{}
+
+// Port is read from the path like the uint16 it is.
+type Port uint16
+
+//goge:api method=GET path=/text
+func (s *service) Text(ctx context.Context) (*template.Template, error) {
+	return template.New("text"), nil
+}
+
+//goge:api method=GET path=/addr/:addr
+func (s *service) Lookup(ctx context.Context, addr netip.Addr) (string, error) {
+	return addr.String(), nil
+}
+
+//goge:api method=GET path=/port/:port
+func (s *service) Open(ctx context.Context, port Port) (int, error) {
+	return int(port), nil
+}
@@ -0,0 +1,35 @@
+package users
+
+import "context"
+
+type Role string
+
+const (
+	Admin  Role = "admin"
+	Member Role = "member"
+)
+
+type ListReq struct {
+	Role Role   `gogeQuery:"role"`
+	Org  string `gogeUrl:"org"`
+}
+
+type User struct {
+	ID   string `json:"id"`
+	Role Role   `json:"role"`
+}
+
+type Event struct {
+	Name string `json:"name"`
+}
+
+type Service struct{}
+
+//goge:api method=GET path=/orgs/:org/users
+func (s *Service) List(ctx context.Context, req *ListReq) ([]User, error) { return nil, nil }
+
+//goge:api method=GET path=/events
+func (s *Service) Events(ctx context.Context) (<-chan Event, error) { return nil, nil }
+
+//goge:api method=GET path=/users/:id
+func Get(ctx context.Context, id string) (*User, error) { return nil, nil }
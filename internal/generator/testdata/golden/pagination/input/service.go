@@ -0,0 +1,45 @@
+package pagination
+
+import (
+	"context"
+
+	"github.com/xehrad/goge/pkg/goge"
+)
+
+type service struct{}
+
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Event struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+}
+
+type ListUsersReq struct {
+	goge.Page `gogePage:"limit=25,max=200"`
+	// Status filters users by account state.
+	Status string `gogeQuery:"status"`
+}
+
+type ListEventsReq struct {
+	goge.Cursor
+	Kind string `gogeQuery:"kind"`
+}
+
+//goge:api method=GET path=/users
+func (s *service) ListUsers(ctx context.Context, req *ListUsersReq) (*goge.PageResult[User], error) {
+	return &goge.PageResult[User]{Items: []User{}}, nil
+}
+
+//goge:api method=GET path=/events
+func (s *service) ListEvents(ctx context.Context, req ListEventsReq) (goge.PageResult[Event], error) {
+	return goge.PageResult[Event]{Items: []Event{}}, nil
+}
+
+//goge:api method=GET path=/recent
+func (s *service) Recent(ctx context.Context) (goge.PageResult[Event], error) {
+	return goge.PageResult[Event]{}, nil
+}
@@ -0,0 +1,50 @@
+package catalog
+
+type service struct{}
+
+type slug string
+
+type Item struct {
+	ID string `json:"id"`
+}
+
+type FlightReq struct {
+	From string `gogeUrl:"from"`
+	To   string `gogeUrl:"to"`
+	Day  int    `gogeQuery:"day"`
+}
+
+type FileReq struct {
+	Name string `gogeUrl:"name"`
+	Ext  string `gogeUrl:"ext"`
+	Rest string `gogeUrl:"*"`
+}
+
+//goge:api method=GET path=/items/:id<int>
+func (s *service) GetItem(id int) (*Item, error) { return nil, nil }
+
+//goge:api method=GET path=/items/by-size/:size
+func (s *service) BySize(size int32) ([]Item, error) { return nil, nil }
+
+//goge:api method=GET path=/items/:id/stock/:available?
+func (s *service) Stock(req *StockReq) error { return nil }
+
+//goge:api method=GET path=/tags/:type
+func (s *service) Tag(t slug) ([]Item, error) { return nil, nil }
+
+//goge:api method=GET path=/search
+func (s *service) Search(inStock bool) ([]Item, error) { return nil, nil }
+
+//goge:api method=GET path=/flights/:from-:to
+func (s *service) Flights(req *FlightReq) ([]Item, error) { return nil, nil }
+
+//goge:api method=GET path=/files/:name.:ext/*
+func (s *service) File(req *FileReq) error { return nil }
+
+//goge:api method=GET path=/static/*
+func (s *service) Static(path string) error { return nil }
+
+type StockReq struct {
+	ID        string `gogeUrl:"id"`
+	Available string `gogeUrl:"available"`
+}
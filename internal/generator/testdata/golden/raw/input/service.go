@@ -0,0 +1,29 @@
+package files
+
+import (
+	"io"
+
+	"github.com/xehrad/goge/pkg/goge"
+)
+
+type FileReq struct {
+	Name string `gogeUrl:"name"`
+}
+
+type Doc struct {
+	Title string `json:"title" xml:"title"`
+}
+
+type service struct{}
+
+//goge:api method=GET path=/export.csv contentType=text/csv
+func (s *service) Export() ([]byte, error) { return nil, nil }
+
+//goge:api method=GET path=/stream/:name
+func (s *service) Stream(req *FileReq) (io.ReadCloser, error) { return nil, nil }
+
+//goge:api method=GET path=/files/:name
+func (s *service) Download(req *FileReq) (*goge.File, error) { return nil, nil }
+
+//goge:api method=PUT path=/docs produces=json,xml,msgpack
+func (s *service) PutDoc(req *Doc) (*Doc, error) { return nil, nil }
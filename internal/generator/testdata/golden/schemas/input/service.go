@@ -0,0 +1,62 @@
+package schemas
+
+import (
+	"context"
+	"strconv"
+)
+
+type service struct{}
+
+// Node is a tree of documents.
+type Node struct {
+	Name     string  `json:"name"`
+	Children []*Node `json:"children,omitempty"`
+	Parent   *Node   `json:"parent,omitempty"`
+}
+
+// Money encodes itself as "12.50 EUR".
+type Money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(m.Cents/100, 10) + " " + m.Currency)), nil
+}
+
+type Code struct {
+	Prefix string
+	N      int
+}
+
+func (c *Code) MarshalText() ([]byte, error) {
+	return []byte(c.Prefix + strconv.Itoa(c.N)), nil
+}
+
+type Meta struct {
+	Version int `json:"version"`
+}
+
+type Audit struct {
+	By string `json:"by,omitempty"`
+}
+
+type Doc struct {
+	Meta
+	Audit  `json:"audit"`
+	Owner  string              `gogeHeader:"X-Owner"`
+	Count  int64               `json:"count,string"`
+	Price  Money               `json:"price"`
+	Code   Code                `json:"code"`
+	Labels map[string][]string `json:"labels,omitempty"`
+	// Root is the tree the document belongs to.
+	Root   *Node  `json:"root"`
+	Secret string `json:"-"`
+	Dash   string `json:"-,"`
+	note   string
+}
+
+//goge:api method=POST path=/docs status=201
+func (s *service) CreateDoc(ctx context.Context, req *Doc) (*Node, error) {
+	return &Node{Name: req.By, Parent: nil}, nil
+}
@@ -0,0 +1,31 @@
+package shop
+
+import "context"
+
+type Product struct {
+	ID string `json:"id"`
+}
+
+type ListReq struct {
+	Query string `gogeQuery:"q"`
+}
+
+type ProductService struct{}
+
+//goge:api method=GET path=/products
+func (s *ProductService) List(ctx context.Context, req *ListReq) ([]Product, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/products/:id
+func (s *ProductService) Get(ctx context.Context, id string) (*Product, error) { return nil, nil }
+
+type adminService struct{}
+
+//goge:api method=GET path=/admin/products auth=bearer
+func (a adminService) List(ctx context.Context, req *ListReq) ([]Product, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/version
+func Version() (string, error) { return "1", nil }
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/xehrad/goge/pkg/goge"
+)
+
+type JobReq struct {
+	ID string `gogeUrl:"id"`
+}
+
+type Progress struct {
+	Percent int `json:"percent"`
+}
+
+type ChatMsg struct {
+	Text string `json:"text"`
+}
+
+type ChatReply struct {
+	Echo string `json:"echo"`
+}
+
+type service struct{}
+
+//goge:api method=GET path=/jobs/:id/progress
+func (s *service) Progress(ctx context.Context, req *JobReq) (<-chan Progress, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/jobs/:id/watch
+func (s *service) Watch(ctx context.Context, req *JobReq, sink goge.EventWriter) error {
+	return nil
+}
+
+//goge:api method=WS path=/chat
+func (s *service) Chat(ctx context.Context, in <-chan ChatMsg, out chan<- ChatReply) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+// Package versions serves v1 of its endpoints unless they say otherwise.
+//
+//goge:version v1
+package versions
+
+import "context"
+
+type service struct{}
+
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserV2 struct {
+	ID       string `json:"id"`
+	FullName string `json:"fullName"`
+}
+
+type GetReq struct {
+	ID string `gogeUrl:"id"`
+}
+
+//goge:api method=GET path=/users
+func (s *service) ListUsers(ctx context.Context) ([]User, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/users version=v2
+func (s *service) ListUsersV2(ctx context.Context) ([]UserV2, error) {
+	return nil, nil
+}
+
+//goge:api method=GET path=/users/:id version=v2
+func GetUser(ctx context.Context, req *GetReq) (*UserV2, error) {
+	return &UserV2{ID: req.ID}, nil
+}
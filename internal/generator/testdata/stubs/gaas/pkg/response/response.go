@@ -0,0 +1,4 @@
+// Package response stubs the response envelope imported by generated handlers.
+package response
+
+func ResponseDataOK(data any) any { return data }
@@ -0,0 +1,22 @@
+// Package websocket stubs the parts of github.com/gofiber/contrib/websocket used by generated handlers.
+package websocket
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Conn struct{}
+
+func (c *Conn) ReadJSON(v any) error                                                { return nil }
+func (c *Conn) WriteJSON(v any) error                                               { return nil }
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error { return nil }
+func (c *Conn) SetReadLimit(limit int64)                                            {}
+func (c *Conn) SetReadDeadline(t time.Time) error                                   { return nil }
+func (c *Conn) SetPongHandler(h func(appData string) error)                         {}
+func (c *Conn) Close() error                                                        { return nil }
+
+func IsWebSocketUpgrade(c *fiber.Ctx) bool { return false }
+
+func New(handler func(*Conn), config ...any) fiber.Handler { return nil }
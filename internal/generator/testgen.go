@@ -0,0 +1,247 @@
+package generator
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+var testTpl = template.Must(template.New("handler_test").Parse(`
+	package {{.PkgName}}
+
+	import (
+		{{- range .Imports }}
+			{{ . }}
+		{{- end }}
+	)
+
+	{{- if .UsesAuth }}
+
+	// gogeAllow accepts every request, so the tests only exercise binding.
+	var gogeAllow = goge.AuthenticatorFunc(func(context.Context, goge.Credentials) (any, error) { return nil, nil })
+	{{- end }}
+
+	func gogeServe(t *testing.T, h interface{ RegisterRoutes(*fiber.App) }, req *http.Request) {
+		t.Helper()
+		app := fiber.New()
+		h.RegisterRoutes(app)
+		if _, err := app.Test(req, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{{- range .Tests }}
+
+	func TestGoge{{ .Name }}(t *testing.T) {
+		svc := &{{ .Mock }}{}
+		req := httptest.NewRequest({{ printf "%q" .Method }}, {{ printf "%q" .URL }}, {{ if .Body }}strings.NewReader("{}"){{ else }}nil{{ end }})
+		{{- if .Body }}
+		req.Header.Set("Content-Type", "application/json")
+		{{- end }}
+		{{- range .Headers }}
+		req.Header.Set({{ printf "%q" .Key }}, {{ printf "%q" .Value }})
+		{{- end }}
+		{{- range .Cookies }}
+		req.AddCookie(&http.Cookie{Name: {{ printf "%q" .Key }}, Value: {{ printf "%q" .Value }}})
+		{{- end }}
+		gogeServe(t, {{ .Ctor }}(svc{{ if .Auth }}, gogeAllow{{ end }}), req)
+
+		calls := svc.{{ .MethodName }}Calls()
+		if len(calls) == 0 {
+			t.Fatal("service {{ .MethodName }} was not called")
+		}
+		got := calls[0].Req
+		{{- range .Asserts }}
+		if got.{{ .Field }} != {{ .Want }} {
+			t.Errorf("{{ .Field }} = %v, want %v", got.{{ .Field }}, {{ .Want }})
+		}
+		{{- end }}
+	}
+	{{- end }}
+`))
+
+type testKV struct {
+	Key   string
+	Value string
+}
+
+type testAssert struct {
+	Field string
+	Want  string // Go literal
+}
+
+type testCaseVM struct {
+	Name       string
+	Mock       string // mock of the endpoint's service
+	Ctor       string // handler constructor of the endpoint's service
+	Auth       bool   // Ctor takes an Authenticator
+	MethodName string
+	Method     string
+	URL        string
+	Body       bool
+	Headers    []testKV
+	Cookies    []testKV
+	Asserts    []testAssert
+}
+
+type testPkgVM struct {
+	PkgName  string
+	Imports  []string
+	UsesAuth bool // some constructor takes an Authenticator; tests pass gogeAllow
+	Tests    []testCaseVM
+}
+
+// buildTestCases returns a binding test, and a defaults test when defaults are declared,
+// for an endpoint with a struct input.
+func buildTestCases(ev endpointVM) []testCaseVM {
+	if ev.Func || ev.ManualFunc != "" || ev.Stream != "" || !ev.InputIsStruct || len(ev.Binds) == 0 ||
+		ev.ReturnKind == "reader" {
+		return nil
+	}
+
+	base := testCaseVM{
+		MethodName: ev.MethodName,
+		Method:     strings.ToUpper(ev.HTTPMethod),
+		Body:       ev.NeedsBodyParser,
+	}
+
+	r := parseRoute(ev.HTTPMethod, ev.Path)
+	inPath := map[string]bool{}
+	for _, key := range r.Keys() {
+		inPath[key] = true
+	}
+	urlValues := map[string]string{}
+	query := url.Values{}
+	bind := base
+	bind.Name = ev.MethodName + "_Bindings"
+	def := base
+	def.Name = ev.MethodName + "_Defaults"
+
+	credential := ""
+	if ev.Auth != nil {
+		var kv testKV
+		kv, credential = testCredential(*ev.Auth)
+		switch credential {
+		case "query":
+			query.Set(kv.Key, kv.Value)
+		case "cookie":
+			bind.Cookies = append(bind.Cookies, kv)
+			def.Cookies = append(def.Cookies, kv)
+		default:
+			bind.Headers = append(bind.Headers, kv)
+			def.Headers = append(def.Headers, kv)
+		}
+		credential = strings.ToLower(credential + ":" + kv.Key)
+	}
+
+	for _, b := range ev.Binds {
+		sample, want, ok := sampleValue(b)
+		if !ok || strings.ToLower(b.Kind+":"+b.Key) == credential {
+			// the credentials already fill this field
+			continue
+		}
+		switch b.Kind {
+		case "url":
+			if !inPath[b.Key] {
+				continue
+			}
+			urlValues[b.Key] = sample
+		case "query":
+			query.Set(b.Key, sample)
+		case "header":
+			bind.Headers = append(bind.Headers, testKV{b.Key, sample})
+		case "cookie":
+			bind.Cookies = append(bind.Cookies, testKV{b.Key, sample})
+		default:
+			continue
+		}
+		bind.Asserts = append(bind.Asserts, testAssert{b.Name, want})
+		if b.HasDefault && b.Kind != "url" {
+			kind := kindString
+			if b.Kind == "query" || b.Enum != nil {
+				kind = b.KindHint
+			}
+			def.Asserts = append(def.Asserts, testAssert{b.Name, defaultLiteral(b.DefaultValue, kind)})
+		}
+	}
+
+	for k, v := range urlValues {
+		urlValues[k] = url.PathEscape(v)
+	}
+	path := r.Build(urlValues, "x")
+	bind.URL = path
+	if len(query) > 0 {
+		bind.URL += "?" + query.Encode()
+	}
+	def.URL = path
+	if ev.Auth != nil && ev.Auth.Type == "apiKey" && ev.Auth.In == "query" {
+		def.URL += "?" + url.Values{ev.Auth.Key: {"test"}}.Encode()
+	}
+
+	out := []testCaseVM{}
+	if len(bind.Asserts) > 0 {
+		out = append(out, bind)
+	}
+	if len(def.Asserts) > 0 {
+		out = append(out, def)
+	}
+	return out
+}
+
+// testCredential returns credentials for a scheme and where they go: "header",
+// "query" or "cookie".
+func testCredential(s scanner.SecurityScheme) (testKV, string) {
+	switch {
+	case s.Type == "apiKey" && s.In == "query":
+		return testKV{s.Key, "test"}, "query"
+	case s.Type == "apiKey" && s.In == "cookie":
+		return testKV{s.Key, "test"}, "cookie"
+	case s.Type == "apiKey":
+		return testKV{s.Key, "test"}, "header"
+	case s.Type == "http" && s.Scheme == "basic":
+		return testKV{"Authorization", "Basic dGVzdDp0ZXN0"}, "header" // test:test
+	default:
+		return testKV{"Authorization", "Bearer test"}, "header"
+	}
+}
+
+// sampleValue picks a request value for a binding and the Go literal it should bind to.
+// Bindings the generated handler cannot convert are skipped.
+func sampleValue(b FieldBind) (sample, want string, ok bool) {
+	if b.Enum != nil {
+		lits := b.Enum.Literals()
+		if len(lits) == 0 {
+			return "", "", false
+		}
+		return strings.Trim(lits[0], `"`), lits[0], true
+	}
+	if b.Kind != "query" && b.KindHint != kindString {
+		return "", "", false
+	}
+	switch b.KindHint {
+	case kindInt:
+		return "7", "7", true
+	case kindFloat:
+		return "1.5", "1.5", true
+	case kindBool:
+		return "true", "true", true
+	default:
+		v := "v-" + strings.ToLower(b.Key)
+		if b.Kind == "url" {
+			// no delimiters: Fiber splits segments such as `:from-:to` on them
+			v = "v" + strings.Trim(strings.ToLower(b.Key), "*+")
+		}
+		return v, fmt.Sprintf("%q", v), true
+	}
+}
+
+// namedResults turns a result type into named results so fakes can use a bare `return`.
+func namedResults(returnType string) string {
+	if returnType == "" {
+		return "(err error)"
+	}
+	return fmt.Sprintf("(res %s, err error)", returnType)
+}
@@ -0,0 +1,50 @@
+package generator
+
+import "testing"
+
+func TestBuildTestCases(t *testing.T) {
+	ev := endpointVM{
+		MethodName:    "GetUser",
+		HTTPMethod:    "Get",
+		Path:          "/users/:id/:other",
+		InputIsStruct: true,
+		InputTypeExpr: "*GetUserReq",
+		HasContext:    true,
+		Binds: []FieldBind{
+			{Name: "ID", Kind: "url", Key: "id", KindHint: kindString},
+			{Name: "Orphan", Kind: "url", Key: "missing", KindHint: kindString},
+			{Name: "Page", Kind: "query", Key: "page", QueryFunc: "QueryInt", KindHint: kindInt, HasDefault: true, DefaultValue: "1"},
+			{Name: "Token", Kind: "header", Key: "X-Token", KindHint: kindString},
+			{Name: "Count", Kind: "header", Key: "X-Count", KindHint: kindInt},
+		},
+	}
+
+	cases := buildTestCases(ev)
+	if len(cases) != 2 {
+		t.Fatalf("want bindings and defaults cases, got %d", len(cases))
+	}
+	bind, def := cases[0], cases[1]
+	if bind.URL != "/users/vid/x?page=7" || def.URL != "/users/vid/x" {
+		t.Fatalf("urls: %q %q", bind.URL, def.URL)
+	}
+	if len(bind.Headers) != 1 || bind.Headers[0] != (testKV{"X-Token", "v-x-token"}) {
+		t.Fatalf("headers: %v", bind.Headers)
+	}
+	want := []testAssert{{"ID", `"vid"`}, {"Page", "7"}, {"Token", `"v-x-token"`}}
+	if len(bind.Asserts) != len(want) {
+		t.Fatalf("asserts: %v", bind.Asserts)
+	}
+	for i, a := range want {
+		if bind.Asserts[i] != a {
+			t.Fatalf("assert %d: got %v want %v", i, bind.Asserts[i], a)
+		}
+	}
+	if len(def.Asserts) != 1 || def.Asserts[0] != (testAssert{"Page", "1"}) {
+		t.Fatalf("defaults: %v", def.Asserts)
+	}
+
+	ev.ManualFunc = "Custom"
+	if len(buildTestCases(ev)) != 0 {
+		t.Fatal("manual handlers are not tested")
+	}
+}
@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+type registeredRoute struct {
+	route
+	ep      *scanner.Endpoint
+	version bool // the unversioned path of a versioned endpoint, dispatched on Accept-Version
+}
+
+func (r registeredRoute) String() string {
+	if r.version {
+		return fmt.Sprintf("%s %s (Accept-Version %s)", r.Method, r.Path, r.ep.Version)
+	}
+	return r.Method + " " + r.Path
+}
+
+// endpointRoutes are the routes ep registers: its path and, for versioned endpoints,
+// the path without the version prefix.
+func endpointRoutes(ep *scanner.Endpoint) []registeredRoute {
+	routes := []registeredRoute{{parseRoute(ep.HTTPMethod, ep.Path), ep, false}}
+	if ep.Version != "" {
+		routes = append(routes, registeredRoute{parseRoute(ep.HTTPMethod, ep.BasePath), ep, true})
+	}
+	return routes
+}
+
+// Validate checks the routes of all packages before anything is written: duplicate
+// routes (also across packages, versions of one path aside), routes shadowed by one
+// registered earlier in the same RegisterRoutes, path parameters no gogeUrl field binds
+// and gogeUrl fields without a matching path segment, primitive inputs that cannot be
+// read from a string, the security schemes endpoints refer to, what package-level
+// functions cannot do, invalid pagination inputs and //goge:example functions that are
+// not literals. Every problem is reported with its position.
+func Validate(root string, apis map[string]*scanner.PackageAPIs) error {
+	dirs := sortedDirs(apis)
+
+	var errs []error
+	var all []registeredRoute
+	for _, dir := range dirs {
+		pkg := apis[dir]
+		// RegisterRoutes registers endpoints in method name order
+		eps := make([]*scanner.Endpoint, len(pkg.Endpoints))
+		for i := range pkg.Endpoints {
+			eps[i] = &pkg.Endpoints[i]
+		}
+		sort.Slice(eps, func(i, j int) bool { return eps[i].MethodName < eps[j].MethodName })
+
+		services := packageServices(pkg)
+		local := []registeredRoute{}
+		for _, ep := range eps {
+			routes := endpointRoutes(ep)
+			for _, r := range routes {
+				for _, prev := range local {
+					if !prev.Covers(r.route) || (prev.version && r.version) {
+						// versions of one path pass the requests for other versions on
+						continue
+					}
+					if r.Covers(prev.route) {
+						errs = append(errs, fmt.Errorf("%s: %s: duplicate route %s, already registered by %s", ep.Pos, ep.MethodName, r, prev.ep.MethodName))
+						break
+					}
+					if receiverType(prev.ep.RecvName) != receiverType(ep.RecvName) {
+						// each service's RegisterRoutes and GogeRouter run in the order the caller picks
+						continue
+					}
+					errs = append(errs, fmt.Errorf("%s: %s: route %s is shadowed by %s of %s, which is registered first (routes are registered in method name order)", ep.Pos, ep.MethodName, r, prev, prev.ep.MethodName))
+					break
+				}
+				for _, other := range all {
+					if other.ep.PkgDir != ep.PkgDir && !(other.version && r.version) && other.Covers(r.route) && r.Covers(other.route) {
+						errs = append(errs, fmt.Errorf("%s: %s: duplicate route %s, also registered by %s.%s at %s", ep.Pos, ep.MethodName, r, other.ep.PkgName, other.ep.MethodName, other.ep.Pos))
+						break
+					}
+				}
+			}
+			errs = append(errs, checkPathParams(root, pkg, ep, routes[0].route)...)
+			errs = append(errs, checkPage(root, pkg, ep)...)
+			errs = append(errs, checkFunc(services, ep)...)
+			local = append(local, routes...)
+		}
+		all = append(all, local...)
+		_, exampleErrs := packageExamples(pkg)
+		errs = append(errs, exampleErrs...)
+	}
+	errs = append(errs, checkEndpointSchemes(root, apis)...)
+	return errors.Join(errs...)
+}
+
+// checkPathParams matches the named path parameters against the gogeUrl fields of the DTO.
+func checkPathParams(root string, pkg *scanner.PackageAPIs, ep *scanner.Endpoint, r route) []error {
+	if ep.ManualFunc != "" {
+		// the manual handler reads the path itself
+		return nil
+	}
+	params := r.Params()
+	if ep.InputTypeExpr == "" {
+		if len(params) == 0 {
+			return nil
+		}
+		return []error{fmt.Errorf("%s: %s: path parameter :%s of %s is not bound: the method takes no input", ep.Pos, ep.MethodName, strings.Join(params, ", :"), ep.Path)}
+	}
+	if !ep.InputIsStruct {
+		if inputKind(loadTypes(pkg.PkgDir), ep.Pos.Filename, ep.InputTypeExpr) == "" {
+			return []error{fmt.Errorf("%s: %s: input %s is neither a basic type nor an encoding.TextUnmarshaler: use a DTO", ep.Pos, ep.MethodName, ep.InputTypeExpr)}
+		}
+		if keys := r.Keys(); len(keys) > 1 {
+			return []error{fmt.Errorf("%s: %s: %s has %d path parameters (%s) but a %s input binds only one: use a DTO with %s fields", ep.Pos, ep.MethodName, ep.Path, len(keys), strings.Join(keys, ", "), ep.InputTypeExpr, _TAG_URL)}
+		}
+		return nil
+	}
+	st := findStructAST(root, pkg, strings.TrimPrefix(ep.InputTypeExpr, "*"))
+	if st == nil {
+		return nil
+	}
+
+	inPath := map[string]bool{}
+	for _, p := range r.Keys() {
+		inPath[p] = true
+	}
+	bound := map[string]bool{}
+	var errs []error
+	for _, b := range ExtractBindingsRecursive(pkg, st) {
+		if b.Kind != "url" {
+			continue
+		}
+		bound[b.Key] = true
+		if !inPath[b.Key] {
+			errs = append(errs, fmt.Errorf("%s: %s: field %s has %s:%q but %s has no :%s segment", ep.Pos, ep.MethodName, b.Name, _TAG_URL, b.Key, ep.Path, b.Key))
+		}
+	}
+	for _, p := range params {
+		if !bound[p] {
+			errs = append(errs, fmt.Errorf("%s: %s: path parameter :%s is not bound: %s has no %s:%q field", ep.Pos, ep.MethodName, p, strings.TrimPrefix(ep.InputTypeExpr, "*"), _TAG_URL, p))
+		}
+	}
+	return errs
+}
+
+// checkPage reports invalid gogePage tags and inputs embedding several of goge.Page and
+// goge.Cursor, which would all read ?limit=.
+func checkPage(root string, pkg *scanner.PackageAPIs, ep *scanner.Endpoint) []error {
+	if ep.ManualFunc != "" || !ep.InputIsStruct {
+		return nil
+	}
+	st := findStructAST(root, pkg, strings.TrimPrefix(ep.InputTypeExpr, "*"))
+	if st == nil {
+		return nil
+	}
+	var errs []error
+	var pages []string
+	for _, b := range ExtractBindingsRecursive(pkg, st) {
+		if b.Kind != "page" && b.Kind != "cursor" {
+			continue
+		}
+		pages = append(pages, "goge."+b.Name)
+		if b.Options == nil {
+			continue
+		}
+		if _, _, err := parsePageTag(strings.Join(b.Options, ",")); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: invalid %s tag on goge.%s: %v", ep.Pos, ep.MethodName, _TAG_PAGE, b.Name, err))
+		}
+	}
+	if len(pages) > 1 {
+		errs = append(errs, fmt.Errorf("%s: %s: %s embeds %s: keep one", ep.Pos, ep.MethodName, strings.TrimPrefix(ep.InputTypeExpr, "*"), strings.Join(pages, " and ")))
+	}
+	return errs
+}
+
+// checkFunc reports what package-level function endpoints cannot do: GogeRouter has no
+// Authenticator, and their <Name>Handler must not clash with a generated declaration
+// such as NewHandler.
+func checkFunc(services map[string]*serviceVM, ep *scanner.Endpoint) []error {
+	if !ep.Func {
+		return nil
+	}
+	var errs []error
+	if ep.Auth != "" {
+		errs = append(errs, fmt.Errorf("%s: %s: auth= needs a service method; package-level functions are served by GogeRouter, which has no Authenticator", ep.Pos, ep.MethodName))
+	}
+	name := ep.MethodName + "Handler"
+	for _, svc := range services {
+		if name == svc.Ctor || name == svc.Handler {
+			errs = append(errs, fmt.Errorf("%s: %s: its handler %s clashes with the generated %s; rename the function", ep.Pos, ep.MethodName, name, name))
+			break
+		}
+	}
+	return errs
+}
@@ -0,0 +1,220 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+func TestRouteCovers(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"/users/:id", "/users/:name", true},
+		{"/users/:id", "/users/me", true},
+		{"/users/me", "/users/:id", false},
+		{"/Users/", "/users", true},
+		{"/users/:id?", "/users", true},
+		{"/users/:id", "/users/:id?", false},
+		{"/files/*", "/files/a/b", true},
+		{"/files/+", "/files", false},
+		{"/users/:id", "/users/:id/raw", false},
+		{"/items/:id<int>", "/items/:x", false},
+		{"/items/:id<int>", "/items/new", false},
+		{"/items/:id<int>", "/items/:n<int>", true},
+		{"/items/:id", "/items/:n<int>", true},
+		{"/flights/:a-:b", "/flights/:from-:to", true},
+		{"/flights/:a-:b", "/flights/:a.:b", false},
+		{"/flights/:id", "/flights/:from-:to", true},
+		{`/v1/name\:verb`, "/v1/:name", false},
+	}
+	for _, c := range cases {
+		if got := parseRoute("GET", c.a).Covers(parseRoute("GET", c.b)); got != c.want {
+			t.Errorf("%s covers %s = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+	if parseRoute("GET", "/x").Covers(parseRoute("POST", "/x")) {
+		t.Error("routes with different methods must not overlap")
+	}
+	r := parseRoute("GET", "/files/:name.:ext<alpha>/*1/:rev?")
+	if got := strings.Join(r.Params(), ","); got != "name,ext,rev" {
+		t.Errorf("Params = %v", got)
+	}
+	if got := strings.Join(r.Keys(), ","); got != "name,ext,*1,rev" {
+		t.Errorf("Keys = %v", got)
+	}
+	if got := r.Build(map[string]string{"name": "a", "ext": "txt"}, "x"); got != "/files/a.txt/x/x" {
+		t.Errorf("Build = %v", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	root := t.TempDir()
+	write := func(dir, src string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, "svc.go"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("users", `package users
+
+type service struct{}
+
+type GetReq struct {
+	ID   string `+"`gogeUrl:\"id\"`"+`
+	Slug string `+"`gogeUrl:\"slug\"`"+`
+}
+
+type ListReq struct {
+	Org string `+"`gogeUrl:\"org\"`"+`
+}
+
+//goge:api method=GET path=/users/:id
+func (s *service) Get(req *GetReq) error { return nil }
+
+//goge:api method=GET path=/users/me
+func (s *service) Me() error { return nil }
+
+//goge:api method=GET path=/users/:key
+func (s *service) Lookup(req *GetReq) error { return nil }
+
+//goge:api method=DELETE path=/orgs/:org/users/:user
+func (s *service) Remove(req *ListReq) error { return nil }
+
+//goge:api method=GET path=/orgs/:org
+func (s *service) Org() error { return nil }
+
+//goge:api method=GET path=/teams/:org/:team
+func (s *service) Team(team string) error { return nil }
+`)
+	write("admin", `package admin
+
+type service struct{}
+
+//goge:api method=GET path=/health
+func (s *service) Health() error { return nil }
+
+//goge:api method=POST path=/admin/new
+func New() error { return nil }
+
+//goge:api method=GET path=/admin/secret auth=bearer
+func Secret() error { return nil }
+`)
+	write("ops", `package ops
+
+type service struct{}
+
+//goge:security key type=apiKey in=header name=X-Key
+
+//goge:api method=GET path=/Health/
+func (s *service) Ping() error { return nil }
+
+type StatsReq struct {
+	Caller string `+"`gogeAuth:\"\"`"+`
+}
+
+//goge:api method=GET path=/stats
+func (s *service) Stats(req *StatsReq) error { return nil }
+
+//goge:api method=GET path=/metrics auth=token
+func (s *service) Metrics() error { return nil }
+`)
+	write("billing", `package billing
+
+//goge:security key type=apiKey in=query name=key
+
+type service struct{}
+
+//goge:api method=GET path=/invoices auth=key
+func (s *service) Invoices() error { return nil }
+`)
+
+	write("catalog", `package catalog
+
+type service struct{}
+
+//goge:api method=GET path=/items version=v1
+func (s *service) ItemsV1() error { return nil }
+
+//goge:api method=GET path=/items version=v2
+func (s *service) Items() error { return nil }
+
+//goge:api method=GET path=/products
+func (s *service) Products() error { return nil }
+
+//goge:api method=GET path=/products version=v2
+func (s *service) ProductsV2() error { return nil }
+
+//goge:api method=GET path=/v2/products
+func (s *service) Legacy() error { return nil }
+`)
+
+	apis, err := scanner.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Validate(root, apis)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"svc.go:21:1: Lookup: duplicate route GET /users/:key, already registered by Get",
+		"Me: route GET /users/me is shadowed by GET /users/:id of Get",
+		`Get: field Slug has gogeUrl:"slug" but /users/:id has no :slug segment`,
+		`Lookup: path parameter :key is not bound: GetReq has no gogeUrl:"key" field`,
+		`Remove: path parameter :user is not bound`,
+		"Org: path parameter :org of /orgs/:org is not bound: the method takes no input",
+		"Team: /teams/:org/:team has 2 path parameters (org, team) but a string input binds only one",
+		"Ping: duplicate route GET /Health/, also registered by admin.Health",
+		"Stats: field Caller has gogeAuth but the endpoint has no auth=",
+		`Metrics: unknown security scheme "token"`,
+		"security scheme key is already declared differently",
+		"New: its handler NewHandler clashes with the generated NewHandler",
+		"Secret: auth= needs a service method",
+		"ProductsV2: duplicate route GET /v2/products, already registered by Legacy",
+		"ProductsV2: duplicate route GET /products (Accept-Version v2), already registered by Products",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("missing %q in:\n%s", want, msg)
+		}
+	}
+	if n := len(strings.Split(msg, "\n")); n != 17 {
+		t.Errorf("got %d errors, want 17:\n%s", n, msg)
+	}
+}
+
+func TestValidate_Page(t *testing.T) {
+	root := t.TempDir()
+	src := "package svc\n\nimport \"github.com/xehrad/goge/pkg/goge\"\n\ntype service struct{}\n\n" +
+		"type ListReq struct {\n\tgoge.Page `gogePage:\"limit=50,max=10\"`\n}\n\n" +
+		"type ScanReq struct {\n\tgoge.Page\n\tgoge.Cursor `gogePage:\"max=500\"`\n}\n\n" +
+		"//goge:api method=GET path=/items\nfunc (s *service) List(req *ListReq) error { return nil }\n\n" +
+		"//goge:api method=GET path=/scan\nfunc (s *service) Scan(req *ScanReq) error { return nil }\n"
+	if err := os.WriteFile(filepath.Join(root, "svc.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	apis, err := scanner.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Validate(root, apis)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"invalid gogePage tag on goge.Page: limit=50 is above max=10", "ScanReq embeds goge.Page and goge.Cursor"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+	if n := len(strings.Split(err.Error(), "\n")); n != 2 {
+		t.Errorf("got %d errors:\n%v", n, err)
+	}
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xehrad/goge/internal/scanner"
+)
+
+// versionKey identifies the unversioned route the versions of an endpoint share.
+func versionKey(ep scanner.Endpoint) string {
+	r := parseRoute(ep.HTTPMethod, ep.BasePath)
+	return r.Method + " " + strings.ToLower(r.Path)
+}
+
+// versionFallbacks picks, for every unversioned route, the oldest version: the one
+// serving requests without an Accept-Version header, so that clients written before a
+// new version keep their behavior.
+func versionFallbacks(apis map[string]*scanner.PackageAPIs) map[string]string {
+	out := map[string]string{}
+	for _, pkg := range apis {
+		for _, ep := range pkg.Endpoints {
+			if ep.Version == "" {
+				continue
+			}
+			key := versionKey(ep)
+			if prev, ok := out[key]; !ok || versionLess(ep.Version, prev) {
+				out[key] = ep.Version
+			}
+		}
+	}
+	return out
+}
+
+// versionLess orders versions of the form v<major>[.<minor>].
+func versionLess(a, b string) bool {
+	am, an := splitVersion(a)
+	bm, bn := splitVersion(b)
+	if am != bm {
+		return am < bm
+	}
+	return an < bn
+}
+
+func splitVersion(v string) (major, minor int) {
+	maj, min, _ := strings.Cut(strings.TrimPrefix(v, "v"), ".")
+	major, _ = strconv.Atoi(maj)
+	minor, _ = strconv.Atoi(min)
+	return major, minor
+}
+
+// versionSuccessors maps every versioned endpoint, by versionKey and version, to the
+// next version of its route, if any.
+func versionSuccessors(apis map[string]*scanner.PackageAPIs) map[string]string {
+	versions := map[string][]string{}
+	for _, pkg := range apis {
+		for _, ep := range pkg.Endpoints {
+			if ep.Version != "" {
+				versions[versionKey(ep)] = append(versions[versionKey(ep)], ep.Version)
+			}
+		}
+	}
+	out := map[string]string{}
+	for key, vs := range versions {
+		sort.Slice(vs, func(i, j int) bool { return versionLess(vs[i], vs[j]) })
+		for i := 0; i+1 < len(vs); i++ {
+			out[key+" "+vs[i]] = vs[i+1]
+		}
+	}
+	return out
+}
@@ -1,15 +1,19 @@
 package scanner
 
 import (
-	"maps"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Endpoint struct {
@@ -20,25 +24,154 @@ type Endpoint struct {
 	HTTPMethod     string // GET/POST/PUT/DELETE
 	Path           string // e.g. /user/:id
 	InputIsStruct  bool
-	InputTypeExpr  string // as written in signature, e.g. "*domain.RegisterUser" or "string"
-	ReturnTypeExpr string
+	InputTypeExpr  string // as written in signature, e.g. "*domain.RegisterUser" or "string"; empty when there is no input
+	ReturnTypeExpr string // empty when the method only returns error
 	InputIsPtr     bool
+	HasContext     bool              // first param is context.Context
+	Func           bool              // package-level function instead of a service method
+	FiberCtx       bool              // first param is *fiber.Ctx (functions only)
 	Imports        map[string]string // alias => import path (for generated file)
 	ManualFunc     string
+	Status         int            // success status code, 0 means the default (200, or 204 without result)
+	Stream         string         // "" | StreamChan (returns <-chan T) | StreamSink (takes goge.EventWriter) | StreamWS
+	WSIn           string         // message type read from the client (method=WS)
+	WSOut          string         // reply type written to the client (method=WS)
+	Produces       []string       // media types negotiated via Accept/Content-Type (produces=json,xml)
+	ReturnKind     string         // "" for encoded results, or ReturnBytes/ReturnReader/ReturnFile
+	ContentType    string         // contentType= for raw results
+	Pos            token.Position // position of the annotated method, used in validation errors
+	Summary        string         // first sentence of the doc comment
+	Description    string         // full doc comment when it says more than the summary
+	Tags           []string       // tags= for the OpenAPI operation
+	OperationID    string         // operationId=, empty means the generator picks one
+	Deprecated     bool           // deprecated, also implied by sunset=
+	DeprecatedAt   string         // date of deprecated=2026-06-01, empty for the bare flag
+	Sunset         string         // sunset= date (2006-01-02) after which the endpoint goes away
+	DeprecationURL string         // deprecationLink=, documentation for clients to migrate
+	CacheMaxAge    time.Duration  // cache=, how long clients and the response cache keep the result
+	ETag           bool           // etag, answer If-None-Match with 304
+	Auth           string         // auth= security scheme name
+	Scopes         []string       // scopes= required from the scheme
+	Version        string         // version=, or the package's //goge:version; Path then starts with /<version>
+	BasePath       string         // path as declared, also served to requests with an Accept-Version header
 }
 
+// RuntimeImportPath is the package generated code imports for runtime helpers.
+const RuntimeImportPath = "github.com/xehrad/goge/pkg/goge"
+
+const (
+	StreamChan = "chan"
+	StreamSink = "sink"
+	StreamWS   = "ws"
+)
+
+// Raw result kinds, sent as-is instead of being encoded.
+const (
+	ReturnBytes  = "bytes"  // []byte
+	ReturnReader = "reader" // io.Reader / io.ReadCloser
+	ReturnFile   = "file"   // goge.File / *goge.File
+)
+
 type PackageAPIs struct {
 	PkgDir    string
 	PkgName   string
 	Imports   map[string]string
 	Endpoints []Endpoint
+	Security  []SecurityScheme // //goge:security declarations found in the package
+	Examples  []Example        // //goge:example functions of the package
+}
+
+// Example is a function marked `//goge:example` that returns a populated value, e.g.
+//
+//	//goge:example
+//	func exampleUser() *User { return &User{ID: 7, Name: "Ada"} }
+//
+// The OpenAPI document shows the value for request and response bodies of that type.
+// The generator reads the returned literal; the function is never called.
+type Example struct {
+	Func    string
+	Type    string            // result type, e.g. "*User" or "[]dto.Item"
+	Value   string            // the returned expression
+	Imports map[string]string // alias => import path in the declaring file
+	Pos     token.Position
+}
+
+// SecurityScheme is declared once per project with a package-level comment such as
+// `//goge:security bearerAuth type=http scheme=bearer bearerFormat=JWT` and referenced
+// by name from auth=.
+type SecurityScheme struct {
+	Name             string
+	Type             string // http | apiKey | oauth2 | openIdConnect
+	Scheme           string // bearer | basic, for type=http
+	BearerFormat     string
+	In               string // header | query | cookie, for type=apiKey
+	Key              string // header, query or cookie name, for type=apiKey
+	Description      string
+	Flow             string // implicit | password | clientCredentials | authorizationCode, for type=oauth2
+	AuthorizationURL string
+	TokenURL         string
+	RefreshURL       string
+	Scopes           []string
+	OpenIDConnectURL string
+	Pos              token.Position
 }
 
-// Parse `//goge:api method=POST path=/user`
-var gogeRe = regexp.MustCompile(`^goge:api\s+method=([A-Z]+)\s+path=([^\s]+)(?:\s+manual_func=([A-Za-z0-9_]+))?\s*$`)
+// BuiltinSchemes can be used with auth= without being declared.
+var BuiltinSchemes = map[string]SecurityScheme{
+	"bearer": {Name: "bearer", Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+	"basic":  {Name: "basic", Type: "http", Scheme: "basic"},
+}
+
+// Parse `//goge:api method=POST path=/user [key=value ...] [flag ...]`
+var gogeRe = regexp.MustCompile(`^goge:api\s+(.+)$`)
+
+// Parse `//goge:example`
+var exampleRe = regexp.MustCompile(`^goge:example\s*$`)
+
+// Parse `//goge:version v2`, the version of every endpoint of the package
+var versionRe = regexp.MustCompile(`^goge:version\s+(\S+)\s*$`)
+
+// Parse `//goge:security <name> type=... [key=value ...]`
+var securityRe = regexp.MustCompile(`^goge:security\s+(\S+)\s*(.*)$`)
+
+var (
+	methodRe     = regexp.MustCompile(`^[A-Z]+$`)
+	identRe      = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	majorRe      = regexp.MustCompile(`^v[0-9]+$`)
+	apiVersionRe = regexp.MustCompile(`^v[0-9]+(\.[0-9]+)?$`)
+	knownOptions = map[string]bool{
+		"method":          true,
+		"path":            true,
+		"manual_func":     true,
+		"status":          true,
+		"produces":        true,
+		"contentType":     true,
+		"tags":            true,
+		"operationId":     true,
+		"deprecated":      true,
+		"auth":            true,
+		"scopes":          true,
+		"version":         true,
+		"sunset":          true,
+		"deprecationLink": true,
+		"cache":           true,
+		"etag":            true,
+	}
+
+	// MediaTypes maps produces= names to media types.
+	MediaTypes = map[string]string{
+		"json":    "application/json",
+		"xml":     "application/xml",
+		"msgpack": "application/msgpack",
+		"cbor":    "application/cbor",
+	}
+)
 
 func Scan(root string) (map[string]*PackageAPIs, error) {
 	result := map[string]*PackageAPIs{}
+	security := map[string][]SecurityScheme{}
+	examples := map[string][]Example{}
+	versions := map[string]string{}
 	fset := token.NewFileSet()
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
@@ -48,7 +181,7 @@ func Scan(root string) (map[string]*PackageAPIs, error) {
 		if info.IsDir() {
 			// skip vendor, .git, node_modules, build dirs
 			base := filepath.Base(path)
-			if strings.HasPrefix(base, ".") || base == "vendor" || base == "node_modules" {
+			if path != root && (strings.HasPrefix(base, ".") || base == "vendor" || base == "node_modules" || base == "testdata") {
 				return filepath.SkipDir
 			}
 			return nil
@@ -56,7 +189,7 @@ func Scan(root string) (map[string]*PackageAPIs, error) {
 		if !strings.HasSuffix(path, ".go") {
 			return nil
 		}
-		if strings.HasSuffix(path, "_gen.go") || strings.HasSuffix(path, "handler_gen.go") {
+		if strings.HasSuffix(path, "_gen.go") || strings.HasSuffix(path, "_gen_test.go") {
 			return nil
 		}
 
@@ -76,52 +209,166 @@ func Scan(root string) (map[string]*PackageAPIs, error) {
 			if im.Name != nil {
 				alias = im.Name.Name
 			} else {
-				// default alias is last path element
-				parts := strings.Split(ip, "/")
-				alias = parts[len(parts)-1]
+				alias = ImportName(ip)
 			}
 			imports[alias] = ip
 		}
 
+		schemes, err := parseSecurity(fset, fileAst)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(schemes) > 0 {
+			security[pkgDir] = append(security[pkgDir], schemes...)
+		}
+		version, err := parseVersion(fileAst)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if version != "" {
+			if prev := versions[pkgDir]; prev != "" && prev != version {
+				return fmt.Errorf("%s: //goge:version %s, but the package is already %s", path, version, prev)
+			}
+			versions[pkgDir] = version
+		}
+
 		// check funcs
 		for _, decl := range fileAst.Decls {
 			fn, ok := decl.(*ast.FuncDecl)
 			if !ok || fn.Doc == nil {
 				continue
 			}
-			var httpMethod, httpPath, manualFunc string
+			if isExample(fn) {
+				ex, err := parseExample(fset, fn, imports)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", path, fn.Name.Name, err)
+				}
+				examples[pkgDir] = append(examples[pkgDir], ex)
+				continue
+			}
+			var opts map[string]string
 			for _, c := range fn.Doc.List {
 				txt := strings.TrimPrefix(strings.TrimSpace(c.Text), "//")
 				if m := gogeRe.FindStringSubmatch(txt); m != nil {
-					httpMethod, httpPath = m[1], m[2]
-					manualFunc = m[3]
+					if opts, err = parseOptions(m[1]); err != nil {
+						return fmt.Errorf("%s: %s: %w", path, fn.Name.Name, err)
+					}
 					break
 				}
 			}
-			if httpMethod == "" {
+			if opts == nil {
 				continue
 			} // not annotated
+			httpMethod, httpPath, manualFunc := opts["method"], opts["path"], opts["manual_func"]
+			status := 0
+			if v, ok := opts["status"]; ok {
+				if status, err = strconv.Atoi(v); err != nil || status < 100 || status > 599 {
+					return fmt.Errorf("%s: %s: invalid status %q", path, fn.Name.Name, v)
+				}
+			}
 
-			// only methods with receiver (service methods)
-			if fn.Recv == nil || len(fn.Recv.List) == 0 {
-				return fmt.Errorf("%s: //goge:api must be on a method with receiver", path)
+			// service methods, or package-level functions served by GogeRouter
+			isFunc := fn.Recv == nil || len(fn.Recv.List) == 0
+			recvExpr := ""
+			if !isFunc {
+				recvExpr = exprString(fn.Recv.List[0].Type)
 			}
-			recvExpr := exprString(fn.Recv.List[0].Type)
 
-			// input param
-			if fn.Type.Params == nil || len(fn.Type.Params.List) != 1 {
-				return fmt.Errorf("%s: %s must have exactly ONE input param (DTO)", path, fn.Name.Name)
+			// input param, optionally preceded by a context.Context, or by the
+			// *fiber.Ctx itself for functions
+			params := paramTypes(fn.Type.Params)
+			hasContext := len(params) > 0 && isContextType(params[0], imports)
+			fiberCtx := isFunc && len(params) > 0 && isFiberCtx(params[0], imports)
+			if fiberCtx && httpMethod == "WS" {
+				return fmt.Errorf("%s: %s: method=WS handlers take a context.Context, not *fiber.Ctx", path, fn.Name.Name)
+			}
+			if hasContext || fiberCtx {
+				params = params[1:]
+			}
+			var produces []string
+			if v, ok := opts["produces"]; ok {
+				for _, name := range strings.Split(v, ",") {
+					mt, ok := MediaTypes[name]
+					if !ok {
+						return fmt.Errorf("%s: %s: unknown produces format %q", path, fn.Name.Name, name)
+					}
+					produces = append(produces, mt)
+				}
 			}
-			inTypeExpr := exprString(fn.Type.Params.List[0].Type)
-			inputIsPtr := strings.HasPrefix(inTypeExpr, "*")
-			inputBase := strings.TrimPrefix(inTypeExpr, "*")
-			// heuristic: consider struct-like input if contains a dot (pkg.Type) OR first letter uppercase
-			inputIsStruct := strings.Contains(inputBase, ".") || (len(inputBase) > 0 && strings.ToUpper(inputBase[:1]) == inputBase[:1])
 
-			// extract return type (first one)
-			retTypeExpr := "any"
-			if fn.Type.Results != nil && len(fn.Type.Results.List) > 0 {
-				retTypeExpr = exprString(fn.Type.Results.List[0].Type)
+			stream, wsIn, wsOut := "", "", ""
+			if httpMethod == "WS" {
+				in, out, ok := wsChannels(params)
+				if !hasContext || !ok {
+					return fmt.Errorf("%s: %s must look like func(ctx context.Context, in <-chan Msg, out chan<- Reply) error", path, fn.Name.Name)
+				}
+				stream, wsIn, wsOut = StreamWS, in, out
+				params = nil
+			}
+			if len(params) > 0 && isRuntimeType(params[len(params)-1], imports, "EventWriter") {
+				if fiberCtx {
+					// the sink runs after the handler returned and Fiber reused the Ctx
+					return fmt.Errorf("%s: %s: goge.EventWriter handlers take a context.Context, not *fiber.Ctx", path, fn.Name.Name)
+				}
+				stream = StreamSink
+				params = params[:len(params)-1]
+			}
+			if len(params) > 1 {
+				return fmt.Errorf("%s: %s must have at most ONE input param (DTO), optionally preceded by context.Context or, for functions, *fiber.Ctx", path, fn.Name.Name)
+			}
+			inTypeExpr, inputIsPtr, inputIsStruct := "", false, false
+			if len(params) == 1 {
+				inTypeExpr = exprString(params[0])
+				inputIsPtr = strings.HasPrefix(inTypeExpr, "*")
+				inputBase := strings.TrimPrefix(inTypeExpr, "*")
+				// heuristic: consider struct-like input if contains a dot (pkg.Type) OR first letter uppercase
+				inputIsStruct = strings.Contains(inputBase, ".") || (len(inputBase) > 0 && strings.ToUpper(inputBase[:1]) == inputBase[:1])
+			}
+
+			// results: `error` or `(T, error)`
+			results := paramTypes(fn.Type.Results)
+			if len(results) == 0 || len(results) > 2 || exprString(results[len(results)-1]) != "error" {
+				return fmt.Errorf("%s: %s must return error or (T, error)", path, fn.Name.Name)
+			}
+			retTypeExpr := ""
+			if len(results) == 2 {
+				retTypeExpr = exprString(results[0])
+				if ch, ok := results[0].(*ast.ChanType); ok && ch.Dir == ast.RECV {
+					stream = StreamChan
+				}
+			}
+			returnKind := ""
+			if len(results) == 2 {
+				returnKind = rawReturnKind(results[0], imports)
+			}
+			if (stream == StreamSink || stream == StreamWS) && retTypeExpr != "" {
+				return fmt.Errorf("%s: %s streams its replies and must only return error", path, fn.Name.Name)
+			}
+
+			var maxAge time.Duration
+			if v, ok := opts["cache"]; ok {
+				maxAge, _ = time.ParseDuration(v) // checked by parseOptions
+			}
+			_, etag := opts["etag"]
+			if (maxAge > 0 || etag) && (manualFunc != "" || stream != "" || retTypeExpr == "" || returnKind == ReturnReader || returnKind == ReturnFile) {
+				return fmt.Errorf("%s: %s: cache= and etag need a result encoded by the generated handler", path, fn.Name.Name)
+			}
+
+			var tags []string
+			deprecatedAt := opts["deprecated"]
+			if deprecatedAt == "true" {
+				deprecatedAt = ""
+			}
+			if v, ok := opts["tags"]; ok {
+				tags = strings.Split(v, ",")
+			}
+			summary, description := docText(fn.Doc)
+			var scopes []string
+			if v, ok := opts["scopes"]; ok {
+				if opts["auth"] == "" {
+					return fmt.Errorf("%s: %s: scopes= needs auth=", path, fn.Name.Name)
+				}
+				scopes = strings.Split(v, ",")
 			}
 
 			ep := Endpoint{
@@ -134,9 +381,33 @@ func Scan(root string) (map[string]*PackageAPIs, error) {
 				InputIsStruct:  inputIsStruct,
 				InputTypeExpr:  inTypeExpr,
 				InputIsPtr:     inputIsPtr,
+				HasContext:     hasContext,
+				Func:           isFunc,
+				FiberCtx:       fiberCtx,
 				Imports:        imports,
 				ReturnTypeExpr: retTypeExpr,
 				ManualFunc:     manualFunc,
+				Status:         status,
+				Stream:         stream,
+				WSIn:           wsIn,
+				WSOut:          wsOut,
+				Produces:       produces,
+				ReturnKind:     returnKind,
+				ContentType:    opts["contentType"],
+				Pos:            fset.Position(fn.Pos()),
+				Summary:        summary,
+				Description:    description,
+				Tags:           tags,
+				OperationID:    opts["operationId"],
+				Deprecated:     opts["deprecated"] != "" || opts["sunset"] != "",
+				DeprecatedAt:   deprecatedAt,
+				Sunset:         opts["sunset"],
+				DeprecationURL: opts["deprecationLink"],
+				CacheMaxAge:    maxAge,
+				ETag:           etag,
+				Auth:           opts["auth"],
+				Scopes:         scopes,
+				Version:        opts["version"],
 			}
 
 			pkg := result[pkgDir]
@@ -154,9 +425,371 @@ func Scan(root string) (map[string]*PackageAPIs, error) {
 	if err != nil {
 		return nil, err
 	}
+	// schemes are only kept for packages with endpoints; auth= may name one declared in
+	// any of them
+	for dir, schemes := range security {
+		if pkg := result[dir]; pkg != nil {
+			pkg.Security = schemes
+		}
+	}
+	// endpoints are served under their version's prefix
+	for dir, pkg := range result {
+		for i := range pkg.Endpoints {
+			ep := &pkg.Endpoints[i]
+			if ep.Version == "" {
+				ep.Version = versions[dir]
+			}
+			if ep.Version != "" {
+				ep.BasePath = ep.Path
+				ep.Path = "/" + ep.Version
+				if ep.BasePath != "/" {
+					ep.Path += ep.BasePath
+				}
+			}
+		}
+	}
+	// examples too, but they may be of any type the package can refer to
+	for dir, exs := range examples {
+		if pkg := result[dir]; pkg != nil {
+			pkg.Examples = exs
+		}
+	}
 	return result, nil
 }
 
+func isExample(fn *ast.FuncDecl) bool {
+	for _, c := range fn.Doc.List {
+		if exampleRe.MatchString(strings.TrimPrefix(strings.TrimSpace(c.Text), "//")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExample checks the shape of an //goge:example function: no receiver or
+// parameters, one result and a body that returns a single expression.
+func parseExample(fset *token.FileSet, fn *ast.FuncDecl, imports map[string]string) (Example, error) {
+	if fn.Recv != nil || fn.Type.TypeParams != nil || len(paramTypes(fn.Type.Params)) != 0 {
+		return Example{}, fmt.Errorf("//goge:example needs a function without receiver, type parameters or parameters")
+	}
+	results := paramTypes(fn.Type.Results)
+	if len(results) != 1 {
+		return Example{}, fmt.Errorf("//goge:example functions return exactly one value")
+	}
+	var ret *ast.ReturnStmt
+	if fn.Body != nil && len(fn.Body.List) == 1 {
+		ret, _ = fn.Body.List[0].(*ast.ReturnStmt)
+	}
+	if ret == nil || len(ret.Results) != 1 {
+		return Example{}, fmt.Errorf("//goge:example functions must consist of a single return statement")
+	}
+	var value strings.Builder
+	if err := printer.Fprint(&value, fset, ret.Results[0]); err != nil {
+		return Example{}, err
+	}
+	return Example{
+		Func:    fn.Name.Name,
+		Type:    exprString(results[0]),
+		Value:   value.String(),
+		Imports: imports,
+		Pos:     fset.Position(fn.Pos()),
+	}, nil
+}
+
+// parseVersion reads the //goge:version comment of a file, if any.
+func parseVersion(file *ast.File) (string, error) {
+	version := ""
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			m := versionRe.FindStringSubmatch(strings.TrimPrefix(strings.TrimSpace(c.Text), "//"))
+			if m == nil {
+				continue
+			}
+			if !apiVersionRe.MatchString(m[1]) {
+				return "", fmt.Errorf("invalid //goge:version %q: use v<major>[.<minor>], e.g. v2", m[1])
+			}
+			if version != "" && version != m[1] {
+				return "", fmt.Errorf("//goge:version %s and %s in one file", version, m[1])
+			}
+			version = m[1]
+		}
+	}
+	return version, nil
+}
+
+var securityOptions = map[string]bool{
+	"type": true, "scheme": true, "bearerFormat": true, "in": true, "name": true,
+	"description": true, "flow": true, "authorizationUrl": true, "tokenUrl": true,
+	"refreshUrl": true, "scopes": true, "openIdConnectUrl": true,
+}
+
+// parseSecurity reads the //goge:security comments of a file.
+func parseSecurity(fset *token.FileSet, file *ast.File) ([]SecurityScheme, error) {
+	out := []SecurityScheme{}
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			m := securityRe.FindStringSubmatch(strings.TrimPrefix(strings.TrimSpace(c.Text), "//"))
+			if m == nil {
+				continue
+			}
+			if !identRe.MatchString(m[1]) {
+				return nil, fmt.Errorf("invalid security scheme name %q", m[1])
+			}
+			// description= takes the rest of the line, spaces included
+			fields, description, _ := strings.Cut(m[2], "description=")
+			opts := map[string]string{"description": strings.TrimSpace(description)}
+			for _, f := range strings.Fields(fields) {
+				key, val, _ := strings.Cut(f, "=")
+				if !securityOptions[key] {
+					return nil, fmt.Errorf("security scheme %s: unknown option %q", m[1], key)
+				}
+				opts[key] = val
+			}
+			s := SecurityScheme{
+				Name:             m[1],
+				Type:             opts["type"],
+				Scheme:           strings.ToLower(opts["scheme"]),
+				BearerFormat:     opts["bearerFormat"],
+				In:               opts["in"],
+				Key:              opts["name"],
+				Description:      opts["description"],
+				Flow:             opts["flow"],
+				AuthorizationURL: opts["authorizationUrl"],
+				TokenURL:         opts["tokenUrl"],
+				RefreshURL:       opts["refreshUrl"],
+				OpenIDConnectURL: opts["openIdConnectUrl"],
+				Pos:              fset.Position(c.Pos()),
+			}
+			if v := opts["scopes"]; v != "" {
+				s.Scopes = strings.Split(v, ",")
+			}
+			if err := checkSecurity(s); err != nil {
+				return nil, fmt.Errorf("security scheme %s: %w", s.Name, err)
+			}
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func checkSecurity(s SecurityScheme) error {
+	switch s.Type {
+	case "http":
+		if s.Scheme != "bearer" && s.Scheme != "basic" {
+			return fmt.Errorf("type=http needs scheme=bearer or scheme=basic")
+		}
+	case "apiKey":
+		if s.In != "header" && s.In != "query" && s.In != "cookie" {
+			return fmt.Errorf("type=apiKey needs in=header, in=query or in=cookie")
+		}
+		if s.Key == "" {
+			return fmt.Errorf("type=apiKey needs name=")
+		}
+	case "oauth2":
+		switch s.Flow {
+		case "implicit":
+			if s.AuthorizationURL == "" {
+				return fmt.Errorf("flow=implicit needs authorizationUrl=")
+			}
+		case "password", "clientCredentials":
+			if s.TokenURL == "" {
+				return fmt.Errorf("flow=%s needs tokenUrl=", s.Flow)
+			}
+		case "authorizationCode":
+			if s.AuthorizationURL == "" || s.TokenURL == "" {
+				return fmt.Errorf("flow=authorizationCode needs authorizationUrl= and tokenUrl=")
+			}
+		default:
+			return fmt.Errorf("type=oauth2 needs flow=implicit|password|clientCredentials|authorizationCode")
+		}
+	case "openIdConnect":
+		if s.OpenIDConnectURL == "" {
+			return fmt.Errorf("type=openIdConnect needs openIdConnectUrl=")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", s.Type)
+	}
+	return nil
+}
+
+// parseOptions splits the annotation into `key=value` pairs; bare words are flags set to "true".
+func parseOptions(s string) (map[string]string, error) {
+	opts := map[string]string{}
+	for _, f := range strings.Fields(s) {
+		key, val, ok := strings.Cut(f, "=")
+		if !ok {
+			val = "true"
+		}
+		if !knownOptions[key] {
+			return nil, fmt.Errorf("unknown //goge:api option %q", key)
+		}
+		opts[key] = val
+	}
+	if !methodRe.MatchString(opts["method"]) {
+		return nil, fmt.Errorf("//goge:api requires method=VERB, got %q", opts["method"])
+	}
+	if !strings.HasPrefix(opts["path"], "/") {
+		return nil, fmt.Errorf("//goge:api requires path=/..., got %q", opts["path"])
+	}
+	if v, ok := opts["manual_func"]; ok && !identRe.MatchString(v) {
+		return nil, fmt.Errorf("invalid manual_func %q", v)
+	}
+	if v, ok := opts["operationId"]; ok && !identRe.MatchString(v) {
+		return nil, fmt.Errorf("invalid operationId %q", v)
+	}
+	if v, ok := opts["auth"]; ok && !identRe.MatchString(v) {
+		return nil, fmt.Errorf("invalid auth %q", v)
+	}
+	if v, ok := opts["version"]; ok && !apiVersionRe.MatchString(v) {
+		return nil, fmt.Errorf("invalid version %q: use v<major>[.<minor>], e.g. v2", v)
+	}
+	for _, key := range []string{"deprecated", "sunset"} {
+		v, ok := opts[key]
+		if !ok || (key == "deprecated" && v == "true") {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return nil, fmt.Errorf("invalid %s date %q: use YYYY-MM-DD", key, v)
+		}
+	}
+	if d, s := opts["deprecated"], opts["sunset"]; d != "" && d != "true" && s != "" && s < d {
+		return nil, fmt.Errorf("sunset %s is before the deprecation on %s", s, d)
+	}
+	if v, ok := opts["cache"]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d < time.Second || d%time.Second != 0 {
+			return nil, fmt.Errorf("invalid cache %q: use whole seconds, e.g. cache=60s or cache=5m", v)
+		}
+	}
+	if v, ok := opts["etag"]; ok && v != "true" {
+		return nil, fmt.Errorf("etag is a flag, got etag=%s", v)
+	}
+	_, cache := opts["cache"]
+	_, etag := opts["etag"]
+	if (cache || etag) && opts["method"] != "GET" {
+		return nil, fmt.Errorf("cache= and etag are only for GET endpoints, got method=%s", opts["method"])
+	}
+	if v, ok := opts["deprecationLink"]; ok {
+		if u, err := url.Parse(v); err != nil || (u.Scheme == "" && !strings.HasPrefix(v, "/")) {
+			return nil, fmt.Errorf("invalid deprecationLink %q: use an absolute URL or path", v)
+		}
+	}
+	return opts, nil
+}
+
+// docText splits a doc comment into its first sentence and the full text. The
+// //goge:api line, like any other directive, is not part of the text.
+func docText(doc *ast.CommentGroup) (summary, description string) {
+	text := strings.TrimSpace(doc.Text())
+	if text == "" {
+		return "", ""
+	}
+	first, _, _ := strings.Cut(text, "\n\n")
+	first = strings.Join(strings.Fields(first), " ")
+	if i := strings.Index(first, ". "); i >= 0 {
+		first = first[:i+1]
+	}
+	summary = strings.TrimSuffix(first, ".")
+	// wrapping is not content: a sentence spread over lines is still only the summary
+	if strings.Join(strings.Fields(text), " ") != first {
+		description = text
+	}
+	return summary, description
+}
+
+// paramTypes flattens a field list so `a, b T` yields T twice.
+func paramTypes(fl *ast.FieldList) []ast.Expr {
+	if fl == nil {
+		return nil
+	}
+	out := []ast.Expr{}
+	for _, f := range fl.List {
+		n := max(len(f.Names), 1)
+		for range n {
+			out = append(out, f.Type)
+		}
+	}
+	return out
+}
+
+// FiberImportPath is the Fiber package; functions may take its *Ctx.
+const FiberImportPath = "github.com/gofiber/fiber/v2"
+
+// ImportName is the name an unnamed import of importPath is assumed to declare: the last
+// path element, skipping a major version suffix such as /v2.
+func ImportName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && majorRe.MatchString(name) {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+func isFiberCtx(e ast.Expr, imports map[string]string) bool {
+	star, ok := e.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Ctx" {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && imports[ident.Name] == FiberImportPath
+}
+
+func isContextType(e ast.Expr, imports map[string]string) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && imports[ident.Name] == "context"
+}
+
+func rawReturnKind(e ast.Expr, imports map[string]string) string {
+	if star, ok := e.(*ast.StarExpr); ok {
+		if isRuntimeType(star.X, imports, "File") {
+			return ReturnFile
+		}
+		return ""
+	}
+	if exprString(e) == "[]byte" {
+		return ReturnBytes
+	}
+	if isRuntimeType(e, imports, "File") {
+		return ReturnFile
+	}
+	if sel, ok := e.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Reader" || sel.Sel.Name == "ReadCloser") {
+		if ident, ok := sel.X.(*ast.Ident); ok && imports[ident.Name] == "io" {
+			return ReturnReader
+		}
+	}
+	return ""
+}
+
+// wsChannels matches `in <-chan In, out chan<- Out` and returns both element types.
+func wsChannels(params []ast.Expr) (in, out string, ok bool) {
+	if len(params) != 2 {
+		return "", "", false
+	}
+	rc, ok1 := params[0].(*ast.ChanType)
+	sc, ok2 := params[1].(*ast.ChanType)
+	if !ok1 || !ok2 || rc.Dir != ast.RECV || sc.Dir != ast.SEND {
+		return "", "", false
+	}
+	return exprString(rc.Value), exprString(sc.Value), true
+}
+
+func isRuntimeType(e ast.Expr, imports map[string]string, name string) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	return ok && imports[ident.Name] == RuntimeImportPath
+}
+
 func exprString(e ast.Expr) string {
 	switch v := e.(type) {
 	case *ast.Ident:
@@ -167,8 +800,25 @@ func exprString(e ast.Expr) string {
 		return exprString(v.X) + "." + v.Sel.Name
 	case *ast.ArrayType:
 		return "[]" + exprString(v.Elt)
+	case *ast.IndexExpr:
+		return exprString(v.X) + "[" + exprString(v.Index) + "]"
+	case *ast.IndexListExpr:
+		args := make([]string, len(v.Indices))
+		for i, ix := range v.Indices {
+			args[i] = exprString(ix)
+		}
+		return exprString(v.X) + "[" + strings.Join(args, ", ") + "]"
 	case *ast.MapType:
 		return "map[" + exprString(v.Key) + "]" + exprString(v.Value)
+	case *ast.ChanType:
+		switch v.Dir {
+		case ast.RECV:
+			return "<-chan " + exprString(v.Value)
+		case ast.SEND:
+			return "chan<- " + exprString(v.Value)
+		default:
+			return "chan " + exprString(v.Value)
+		}
 	case *ast.InterfaceType:
 		return "interface{}"
 	case *ast.FuncType:
@@ -0,0 +1,507 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := parseOptions("method=POST path=/users/:id status=201 manual_func=Custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts["method"] != "POST" || opts["path"] != "/users/:id" || opts["status"] != "201" || opts["manual_func"] != "Custom" {
+		t.Fatalf("unexpected options: %v", opts)
+	}
+
+	for _, bad := range []string{
+		"path=/users",                  // missing method
+		"method=get path=/users",       // lowercase verb
+		"method=GET path=users",        // relative path
+		"method=GET path=/users foo=1", // unknown option
+		"method=GET path=/users deprecated=no",
+		"method=GET path=/users sunset=2027-13-01",
+		"method=GET path=/users deprecated=2027-01-01 sunset=2026-01-01", // sunset before deprecation
+		"method=GET path=/users deprecationLink=docs/migrate",
+		"method=GET path=/users cache=1m30",
+		"method=GET path=/users cache=500ms",
+		"method=POST path=/users cache=60s",
+		"method=PUT path=/users etag",
+		"method=GET path=/users etag=yes",
+		"method=GET path=/users operationId=get-user",
+	} {
+		if _, err := parseOptions(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func writeFile(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan_Signatures(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+import (
+	"context"
+	"io"
+
+	"github.com/xehrad/goge/pkg/goge"
+)
+
+type service struct{}
+
+//goge:api method=GET path=/health
+func (s *service) Health() error { return nil }
+
+// Get returns a user. Deleted users are
+// reported as 404.
+//
+//goge:api method=GET path=/users/:id tags=users,admin operationId=getUser deprecated
+func (s *service) Get(ctx context.Context, req *GetReq) (*User, error) { return nil, nil }
+
+// Delete removes a user and every session
+// it still holds.
+//
+//goge:api method=DELETE path=/users/:id
+func (s *service) Delete(req *GetReq) error { return nil }
+
+//goge:api method=GET path=/events
+func (s *service) Events(ctx context.Context) (<-chan Event, error) { return nil, nil }
+
+//goge:api method=GET path=/watch
+func (s *service) Watch(ctx context.Context, sink goge.EventWriter) error { return nil }
+
+//goge:api method=WS path=/chat
+func (s *service) Chat(ctx context.Context, in <-chan Msg, out chan<- Reply) error { return nil }
+
+//goge:api method=GET path=/raw contentType=text/plain
+func (s *service) Raw() (io.Reader, error) { return nil, nil }
+
+//goge:api method=GET path=/file
+func (s *service) File() (*goge.File, error) { return nil, nil }
+`)
+
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := map[string]Endpoint{}
+	for _, ep := range apis[dir].Endpoints {
+		eps[ep.MethodName] = ep
+	}
+
+	if ep := eps["Health"]; ep.InputTypeExpr != "" || ep.ReturnTypeExpr != "" || ep.HasContext {
+		t.Fatalf("Health: %+v", ep)
+	}
+	if ep := eps["Get"]; !ep.HasContext || ep.InputTypeExpr != "*GetReq" || ep.ReturnTypeExpr != "*User" || ep.Pos.Line != 19 {
+		t.Fatalf("Get: %+v", ep)
+	}
+	if ep := eps["Get"]; ep.Summary != "Get returns a user" || ep.Description != "Get returns a user. Deleted users are\nreported as 404." ||
+		strings.Join(ep.Tags, ",") != "users,admin" || ep.OperationID != "getUser" || !ep.Deprecated {
+		t.Fatalf("Get docs: %+v", ep)
+	}
+	if ep := eps["Health"]; ep.Summary != "" || ep.Description != "" {
+		t.Fatalf("Health docs: %+v", ep)
+	}
+	if ep := eps["Delete"]; ep.Summary != "Delete removes a user and every session it still holds" || ep.Description != "" {
+		t.Fatalf("Delete docs: %+v", ep)
+	}
+	if ep := eps["Events"]; ep.Stream != StreamChan || ep.ReturnTypeExpr != "<-chan Event" {
+		t.Fatalf("Events: %+v", ep)
+	}
+	if ep := eps["Watch"]; ep.Stream != StreamSink || ep.InputTypeExpr != "" {
+		t.Fatalf("Watch: %+v", ep)
+	}
+	if ep := eps["Chat"]; ep.Stream != StreamWS || ep.WSIn != "Msg" || ep.WSOut != "Reply" {
+		t.Fatalf("Chat: %+v", ep)
+	}
+	if ep := eps["Raw"]; ep.ReturnKind != ReturnReader || ep.ContentType != "text/plain" {
+		t.Fatalf("Raw: %+v", ep)
+	}
+	if ep := eps["File"]; ep.ReturnKind != ReturnFile {
+		t.Fatalf("File: %+v", ep)
+	}
+}
+
+func TestScan_InvalidSignature(t *testing.T) {
+	for name, src := range map[string]string{
+		"two inputs": `func (s *service) A(a, b string) error { return nil }`,
+		"no error":   `func (s *service) A() string { return "" }`,
+		"bad ws":     "//goge:api method=WS path=/x\nfunc (s *service) A(in <-chan int) error { return nil }",
+	} {
+		dir := t.TempDir()
+		if !strings.Contains(src, "//goge:api") {
+			src = "//goge:api method=GET path=/x\n" + src
+		}
+		writeFile(t, dir, "svc.go", "package svc\n\ntype service struct{}\n\n"+src+"\n")
+		if _, err := Scan(dir); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestScan_Security(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+//goge:security key type=apiKey in=query name=api_key description=Issued per client
+//goge:security oauth type=oauth2 flow=clientCredentials tokenUrl=https://auth.example.com/token scopes=read,write
+
+type service struct{}
+
+//goge:api method=GET path=/items auth=oauth scopes=read
+func (s *service) List() error { return nil }
+`)
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := apis[dir]
+	if len(pkg.Security) != 2 {
+		t.Fatalf("schemes: %+v", pkg.Security)
+	}
+	if s := pkg.Security[0]; s.Name != "key" || s.In != "query" || s.Key != "api_key" || s.Description != "Issued per client" {
+		t.Fatalf("key: %+v", s)
+	}
+	if s := pkg.Security[1]; s.Flow != "clientCredentials" || len(s.Scopes) != 2 {
+		t.Fatalf("oauth: %+v", s)
+	}
+	if ep := pkg.Endpoints[0]; ep.Auth != "oauth" || len(ep.Scopes) != 1 || ep.Scopes[0] != "read" {
+		t.Fatalf("endpoint: %+v", ep)
+	}
+
+	for _, bad := range []string{
+		"//goge:security k type=apiKey in=body name=k",
+		"//goge:security k type=http scheme=digest",
+		"//goge:security k type=oauth2 flow=implicit",
+		"//goge:security k type=apiKey in=header name=k color=red",
+		"//goge:api method=GET path=/x scopes=read\nfunc (s *service) A() error { return nil }",
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "svc.go", "package svc\n\ntype service struct{}\n\n"+bad+"\n")
+		if _, err := Scan(dir); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScan_Funcs(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//goge:api method=POST path=/ping
+func Ping(c *fiber.Ctx, req *PingReq) (*Pong, error) { return nil, nil }
+
+//goge:api method=GET path=/pong
+func Pong(ctx context.Context, req *PingReq) (*Pong, error) { return nil, nil }
+`)
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := map[string]Endpoint{}
+	for _, ep := range apis[dir].Endpoints {
+		eps[ep.MethodName] = ep
+	}
+	if ep := eps["Ping"]; !ep.Func || !ep.FiberCtx || ep.HasContext || ep.InputTypeExpr != "*PingReq" || ep.RecvName != "" {
+		t.Fatalf("Ping: %+v", ep)
+	}
+	if ep := eps["Pong"]; !ep.Func || ep.FiberCtx || !ep.HasContext {
+		t.Fatalf("Pong: %+v", ep)
+	}
+
+	for _, bad := range []string{
+		"//goge:api method=WS path=/x\nfunc A(c *fiber.Ctx, in <-chan int, out chan<- int) error { return nil }",
+		"//goge:api method=GET path=/x\nfunc A(c *fiber.Ctx, sink goge.EventWriter) error { return nil }",
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "svc.go", "package svc\n\nimport (\n\t\"github.com/gofiber/fiber/v2\"\n\t\"github.com/xehrad/goge/pkg/goge\"\n)\n\n"+bad+"\n")
+		if _, err := Scan(dir); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScan_Examples(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+import dto "example.com/app/dto"
+
+type service struct{}
+
+//goge:api method=GET path=/items
+func (s *service) List() ([]dto.Item, error) { return nil, nil }
+
+//goge:example
+func exampleItems() []dto.Item {
+	return []dto.Item{{ID: 1}}
+}
+`)
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exs := apis[dir].Examples
+	if len(exs) != 1 {
+		t.Fatalf("examples: %+v", exs)
+	}
+	if ex := exs[0]; ex.Func != "exampleItems" || ex.Type != "[]dto.Item" || ex.Value != "[]dto.Item{{ID: 1}}" || ex.Imports["dto"] != "example.com/app/dto" {
+		t.Fatalf("example: %+v", ex)
+	}
+
+	for _, bad := range []string{
+		"func (s *service) example() int { return 1 }",
+		"func example(n int) int { return n }",
+		"func example() (int, error) { return 1, nil }",
+		"func example() int { n := 1; return n }",
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "svc.go", "package svc\n\ntype service struct{}\n\n//goge:example\n"+bad+"\n")
+		if _, err := Scan(dir); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScan_Versions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "doc.go", "//goge:version v1\npackage svc\n")
+	writeFile(t, dir, "svc.go", `package svc
+
+type service struct{}
+
+//goge:api method=GET path=/users
+func (s *service) ListUsers() error { return nil }
+
+//goge:api method=GET path=/users version=v2
+func (s *service) ListUsersV2() error { return nil }
+
+//goge:api method=GET path=/
+func (s *service) Index() error { return nil }
+`)
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := map[string]Endpoint{}
+	for _, ep := range apis[dir].Endpoints {
+		eps[ep.MethodName] = ep
+	}
+	for name, want := range map[string][3]string{
+		"ListUsers":   {"v1", "/v1/users", "/users"},
+		"ListUsersV2": {"v2", "/v2/users", "/users"},
+		"Index":       {"v1", "/v1", "/"},
+	} {
+		if ep := eps[name]; ep.Version != want[0] || ep.Path != want[1] || ep.BasePath != want[2] {
+			t.Errorf("%s: version %q, path %q, base path %q", name, ep.Version, ep.Path, ep.BasePath)
+		}
+	}
+
+	for _, bad := range []string{
+		"//goge:version 2\npackage svc\n",
+		"//goge:version v1\n//goge:version v2\npackage svc\n",
+		"package svc\n\ntype service struct{}\n\n//goge:api method=GET path=/x version=latest\nfunc (s *service) A() error { return nil }\n",
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "svc.go", bad)
+		if _, err := Scan(dir); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScan_Deprecation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+type service struct{}
+
+//goge:api method=GET path=/a deprecated
+func (s *service) A() error { return nil }
+
+//goge:api method=GET path=/b deprecated=2026-06-01 sunset=2027-01-01 deprecationLink=https://example.com/migrate
+func (s *service) B() error { return nil }
+
+//goge:api method=GET path=/c sunset=2027-01-01
+func (s *service) C() error { return nil }
+`)
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := map[string]Endpoint{}
+	for _, ep := range apis[dir].Endpoints {
+		eps[ep.MethodName] = ep
+	}
+	for name, want := range map[string][3]string{
+		"A": {"", "", ""},
+		"B": {"2026-06-01", "2027-01-01", "https://example.com/migrate"},
+		"C": {"", "2027-01-01", ""},
+	} {
+		ep := eps[name]
+		if !ep.Deprecated || ep.DeprecatedAt != want[0] || ep.Sunset != want[1] || ep.DeprecationURL != want[2] {
+			t.Errorf("%s: deprecated %v at %q, sunset %q, link %q", name, ep.Deprecated, ep.DeprecatedAt, ep.Sunset, ep.DeprecationURL)
+		}
+	}
+}
+
+func TestScan_Cache(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+type service struct{}
+
+//goge:api method=GET path=/a cache=5m etag
+func (s *service) A() (string, error) { return "", nil }
+
+//goge:api method=GET path=/b etag
+func (s *service) B() ([]byte, error) { return nil, nil }
+`)
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := apis[dir].Endpoints
+	if eps[0].CacheMaxAge != 5*time.Minute || !eps[0].ETag || eps[1].CacheMaxAge != 0 || !eps[1].ETag {
+		t.Fatalf("unexpected cache options: %+v", eps)
+	}
+
+	for _, bad := range []string{
+		"//goge:api method=GET path=/x cache=60s\nfunc (s *service) A() error { return nil }\n",
+		"//goge:api method=GET path=/x etag\nfunc (s *service) A() (io.Reader, error) { return nil, nil }\n",
+		"//goge:api method=GET path=/x etag\nfunc (s *service) A() (<-chan int, error) { return nil, nil }\n",
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "svc.go", "package svc\n\nimport \"io\"\n\nvar _ io.Reader\n\ntype service struct{}\n\n"+bad)
+		if _, err := Scan(dir); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScan_Context(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+import (
+	stdctx "context"
+
+	"example.com/app/trace"
+)
+
+type service struct{}
+
+//goge:api method=GET path=/ping
+func (s *service) Ping(ctx stdctx.Context) error { return nil }
+
+//goge:api method=GET path=/users/:id
+func (s *service) Get(ctx stdctx.Context, id int) (*User, error) { return nil, nil }
+
+//goge:api method=POST path=/users
+func (s *service) Create(req *CreateReq) (*User, error) { return nil, nil }
+
+//goge:api method=GET path=/spans/:id
+func (s *service) Span(id trace.Context) error { return nil }
+`)
+
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := map[string]Endpoint{}
+	for _, ep := range apis[dir].Endpoints {
+		eps[ep.MethodName] = ep
+	}
+	if ep := eps["Ping"]; !ep.HasContext || ep.InputTypeExpr != "" {
+		t.Fatalf("Ping: %+v", ep)
+	}
+	if ep := eps["Get"]; !ep.HasContext || ep.InputTypeExpr != "int" || ep.InputIsStruct {
+		t.Fatalf("Get: %+v", ep)
+	}
+	if ep := eps["Create"]; ep.HasContext || ep.InputTypeExpr != "*CreateReq" {
+		t.Fatalf("Create: %+v", ep)
+	}
+	// a Context of another package is an input like any other
+	if ep := eps["Span"]; ep.HasContext || ep.InputTypeExpr != "trace.Context" {
+		t.Fatalf("Span: %+v", ep)
+	}
+
+	dir = t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+import "context"
+
+type service struct{}
+
+//goge:api method=GET path=/x
+func (s *service) A(req *Req, ctx context.Context) error { return nil }
+`)
+	if _, err := Scan(dir); err == nil || !strings.Contains(err.Error(), "optionally preceded by context.Context") {
+		t.Fatalf("context after the input: %v", err)
+	}
+}
+
+func TestScan_NoPayload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "svc.go", `package svc
+
+import "context"
+
+type service struct{}
+
+//goge:api method=POST path=/cache/flush
+func (s *service) Flush() error { return nil }
+
+//goge:api method=DELETE path=/users/:id
+func (s *service) Delete(ctx context.Context, id string) error { return nil }
+
+//goge:api method=GET path=/stats
+func (s *service) Stats(ctx context.Context) (*Stats, error) { return nil, nil }
+`)
+
+	apis, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := map[string]Endpoint{}
+	for _, ep := range apis[dir].Endpoints {
+		eps[ep.MethodName] = ep
+	}
+	if ep := eps["Flush"]; ep.InputTypeExpr != "" || ep.ReturnTypeExpr != "" || ep.InputIsStruct {
+		t.Fatalf("Flush: %+v", ep)
+	}
+	if ep := eps["Delete"]; ep.InputTypeExpr != "string" || ep.ReturnTypeExpr != "" {
+		t.Fatalf("Delete: %+v", ep)
+	}
+	if ep := eps["Stats"]; ep.InputTypeExpr != "" || ep.ReturnTypeExpr != "*Stats" {
+		t.Fatalf("Stats: %+v", ep)
+	}
+
+	for name, src := range map[string]string{
+		"no results":    `func (s *service) A() {}`,
+		"three results": `func (s *service) A() (int, string, error) { return 0, "", nil }`,
+		"error first":   `func (s *service) A() (error, int) { return nil, 0 }`,
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "svc.go", "package svc\n\ntype service struct{}\n\n//goge:api method=GET path=/x\n"+src+"\n")
+		if _, err := Scan(dir); err == nil || !strings.Contains(err.Error(), "must return error or (T, error)") {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
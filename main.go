@@ -8,15 +8,27 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/xehrad/goge/internal/generator"
 	"github.com/xehrad/goge/internal/scanner"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		schema(os.Args[2:])
+		return
+	}
 
 	root := flag.String("root", ".", "project root to scan")
+	tests := flag.Bool("tests", true, "generate handler_gen_test.go binding tests")
+	spec := flag.String("openapi", "openapi.json", "OpenAPI document written under root; empty disables it")
+	title := flag.String("title", "API", "title of the OpenAPI document")
+	version := flag.String("version", "1.0.0", "version of the OpenAPI document")
+	specVersions := flag.Bool("openapi-versions", false, "also write one OpenAPI document per version, e.g. openapi.v2.json")
+	out := flag.String("out", "", "directory under root to generate the HTTP packages into; empty writes them next to the services")
 	flag.Parse()
 
 	apis, err := scanner.Scan(*root)
@@ -27,8 +39,40 @@ func main() {
 		log.Println("no //goge:api annotations found. nothing to do.")
 		return
 	}
-	if err := generator.Generate(*root, apis); err != nil {
+	if err := generator.Generate(*root, apis, generator.Options{
+		Tests:        *tests,
+		Spec:         *spec,
+		SpecVersions: *specVersions,
+		Title:        *title,
+		Version:      *version,
+		Out:          *out,
+	}); err != nil {
 		log.Fatalf("generate error: %v", err)
 	}
 	log.Printf("goge: generated handlers for %d packages\n", len(apis))
 }
+
+// schema runs `goge schema [-dir pkg] [-o file] [Type ...]`, which writes the JSON Schema
+// of structs of one package, e.g. event contracts, without generating any handler.
+func schema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	dir := fs.String("dir", ".", "package directory declaring the structs")
+	out := fs.String("o", "", "file to write the schema to; empty writes it to stdout")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: goge schema [-dir pkg] [-o file] [Type ...]\n\nWithout types every exported struct of the package is described.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	data, err := generator.JSONSchema(*dir, fs.Args())
+	if err != nil {
+		log.Fatalf("schema error: %v", err)
+	}
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("schema error: %v", err)
+	}
+}
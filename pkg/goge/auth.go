@@ -0,0 +1,79 @@
+package goge
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized makes the generated handler answer 401; it is also returned for
+	// requests without credentials. Any other Authenticator error answers 401 as well,
+	// except Fiber errors, which keep their status.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden makes the generated handler answer 403.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Credentials are read from the request according to the endpoint's security scheme.
+type Credentials struct {
+	Scheme string   // scheme name from auth=, e.g. "bearer"
+	Type   string   // http, apiKey, oauth2 or openIdConnect
+	Value  string   // bearer token, API key, or the base64 part of basic credentials
+	Scopes []string // scopes= required by the endpoint
+}
+
+// BasicAuth decodes Value for schemes of type http with scheme basic.
+func (c Credentials) BasicAuth() (user, password string, ok bool) {
+	raw, err := base64.StdEncoding.DecodeString(c.Value)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(raw), ":")
+}
+
+// Authenticator checks credentials before a generated handler binds its input. The
+// principal it returns is stored in the request context (see PrincipalFrom) and
+// assigned to DTO fields tagged `gogeAuth`.
+type Authenticator interface {
+	Authenticate(ctx context.Context, cred Credentials) (principal any, err error)
+}
+
+// AuthenticatorFunc adapts a function to Authenticator.
+type AuthenticatorFunc func(ctx context.Context, cred Credentials) (any, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, cred Credentials) (any, error) {
+	return f(ctx, cred)
+}
+
+// Authenticate rejects requests without credentials and otherwise asks a. A nil a, e.g.
+// a handler built without an Authenticator, rejects every request.
+func Authenticate(ctx context.Context, a Authenticator, cred Credentials) (any, error) {
+	if cred.Value == "" || a == nil {
+		return nil, ErrUnauthorized
+	}
+	return a.Authenticate(ctx, cred)
+}
+
+// AuthToken returns the credentials of an Authorization header value for the given
+// scheme ("Bearer" or "Basic", matched case-insensitively), or "" when it uses another.
+func AuthToken(header, scheme string) string {
+	prefix, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(prefix, scheme) {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, principal any) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal stored by WithPrincipal, or nil.
+func PrincipalFrom(ctx context.Context) any {
+	return ctx.Value(principalKey{})
+}
@@ -0,0 +1,57 @@
+package goge
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthToken(t *testing.T) {
+	cases := []struct {
+		header, scheme, want string
+	}{
+		{"Bearer abc", "Bearer", "abc"},
+		{"bearer  abc ", "Bearer", "abc"},
+		{"Basic dTpw", "Bearer", ""},
+		{"abc", "Bearer", ""},
+		{"", "Basic", ""},
+	}
+	for _, c := range cases {
+		if got := AuthToken(c.header, c.scheme); got != c.want {
+			t.Errorf("AuthToken(%q, %q) = %q, want %q", c.header, c.scheme, got, c.want)
+		}
+	}
+
+	user, pass, ok := Credentials{Value: "dXNlcjpwYTpzcw=="}.BasicAuth()
+	if !ok || user != "user" || pass != "pa:ss" {
+		t.Fatalf("BasicAuth = %q %q %v", user, pass, ok)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	calls := 0
+	a := AuthenticatorFunc(func(ctx context.Context, cred Credentials) (any, error) {
+		calls++
+		if cred.Value != "good" {
+			return nil, ErrForbidden
+		}
+		return "alice", nil
+	})
+
+	if _, err := Authenticate(context.Background(), nil, Credentials{Value: "good"}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("nil authenticator: %v", err)
+	}
+	if _, err := Authenticate(context.Background(), a, Credentials{}); !errors.Is(err, ErrUnauthorized) || calls != 0 {
+		t.Fatalf("missing credentials: err=%v calls=%d", err, calls)
+	}
+	if _, err := Authenticate(context.Background(), a, Credentials{Value: "bad"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("bad credentials: %v", err)
+	}
+	p, err := Authenticate(context.Background(), a, Credentials{Value: "good"})
+	if err != nil || p != "alice" {
+		t.Fatalf("good credentials: %v %v", p, err)
+	}
+	if got := PrincipalFrom(WithPrincipal(context.Background(), p)); got != "alice" {
+		t.Fatalf("PrincipalFrom = %v", got)
+	}
+}
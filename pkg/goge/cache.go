@@ -0,0 +1,184 @@
+package goge
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is the encoded reply of an endpoint annotated cache= or etag.
+type CachedResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+	ETag        string              // strong ETag of Body, "" without etag
+	Header      map[string][]string // other headers of the reply, e.g. Link, see ReplyHeader
+}
+
+// Cache keeps the responses of endpoints annotated cache=, keyed by CacheKey, for the
+// duration of the annotation. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (CachedResponse, bool)
+	Set(key string, r CachedResponse, ttl time.Duration)
+}
+
+var responseCache = struct {
+	sync.RWMutex
+	c Cache
+}{c: NewLRUCache(1024)}
+
+// SetCache replaces the cache generated handlers keep responses in, by default an
+// in-memory LRU of 1024 responses; nil disables it. Cache-Control and ETag headers are
+// sent either way.
+func SetCache(c Cache) {
+	responseCache.Lock()
+	defer responseCache.Unlock()
+	responseCache.c = c
+}
+
+func currentCache() Cache {
+	responseCache.RLock()
+	defer responseCache.RUnlock()
+	return responseCache.c
+}
+
+// LookupResponse returns the response cached under key, if any.
+func LookupResponse(key string) (CachedResponse, bool) {
+	if c := currentCache(); c != nil {
+		return c.Get(key)
+	}
+	return CachedResponse{}, false
+}
+
+// StoreResponse caches r under key for ttl.
+func StoreResponse(key string, r CachedResponse, ttl time.Duration) {
+	if c := currentCache(); c != nil {
+		c.Set(key, r, ttl)
+	}
+}
+
+// ReplyHeader copies the headers of a reply about to be cached out of h, its response
+// headers: those named in keys and the Set-Cookie values of the cookies named in cookies.
+// Headers set for one request only, e.g. by middleware, are left out. Keys and values are
+// copied, since Fiber's point into a buffer reused by the next request.
+func ReplyHeader(h map[string][]string, keys []string, cookies ...string) map[string][]string {
+	out := map[string][]string{}
+	for k, vs := range h {
+		switch {
+		case strings.EqualFold(k, "Set-Cookie"):
+			for _, v := range vs {
+				name, _, _ := strings.Cut(v, "=")
+				if slices.Contains(cookies, strings.TrimSpace(name)) {
+					out["Set-Cookie"] = append(out["Set-Cookie"], strings.Clone(v))
+				}
+			}
+		case slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(k, key) }):
+			k = strings.Clone(k)
+			for _, v := range vs {
+				out[k] = append(out[k], strings.Clone(v))
+			}
+		}
+	}
+	return out
+}
+
+// CacheKey identifies a response by the route of the endpoint and the values bound
+// from the request: path, query, header and cookie fields, and the negotiated media type.
+func CacheKey(route string, values ...any) string {
+	var sb strings.Builder
+	sb.WriteString(route)
+	for _, v := range values {
+		sb.WriteByte(0)
+		fmt.Fprint(&sb, v)
+	}
+	return sb.String()
+}
+
+// ETag returns a strong entity tag of body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// MatchETag tells whether the If-None-Match header ifNoneMatch matches etag, so that
+// the client's copy is still fresh. Weak tags match their strong counterpart.
+func MatchETag(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// LRUCache is the in-memory Cache used by default: it holds up to a fixed number of
+// responses, evicting the least recently used one first.
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	r       CachedResponse
+	expires time.Time
+}
+
+// NewLRUCache returns an LRUCache of size responses.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{size: max(size, 1), order: list.New(), entries: map[string]*list.Element{}, now: time.Now}
+}
+
+func (l *LRUCache) Get(key string) (CachedResponse, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.entries[key]
+	if !ok {
+		return CachedResponse{}, false
+	}
+	e := el.Value.(*lruEntry)
+	if !l.now().Before(e.expires) {
+		l.order.Remove(el)
+		delete(l.entries, key)
+		return CachedResponse{}, false
+	}
+	l.order.MoveToFront(el)
+	return e.r, true
+}
+
+func (l *LRUCache) Set(key string, r CachedResponse, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := &lruEntry{key: key, r: r, expires: l.now().Add(ttl)}
+	if el, ok := l.entries[key]; ok {
+		el.Value = e
+		l.order.MoveToFront(el)
+		return
+	}
+	l.entries[key] = l.order.PushFront(e)
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of responses held, expired ones included until they are
+// looked up or evicted.
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
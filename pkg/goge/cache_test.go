@@ -0,0 +1,110 @@
+package goge
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestLRUCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewLRUCache(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", CachedResponse{Body: []byte("a")}, time.Minute)
+	c.Set("b", CachedResponse{Body: []byte("b")}, time.Minute)
+	if _, ok := c.Get("a"); !ok { // a is now the most recently used
+		t.Fatal("a missing")
+	}
+	c.Set("c", CachedResponse{Body: []byte("c")}, time.Minute)
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if r, ok := c.Get("a"); !ok || string(r.Body) != "a" {
+		t.Errorf("a: %v %v", r, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("c"); ok {
+		t.Error("c should have expired")
+	}
+	if c.Len() != 1 {
+		t.Errorf("len %d", c.Len())
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	defer SetCache(NewLRUCache(1024))
+	StoreResponse("k", CachedResponse{Status: 200}, time.Minute)
+	if r, ok := LookupResponse("k"); !ok || r.Status != 200 {
+		t.Fatalf("default cache: %v %v", r, ok)
+	}
+	SetCache(nil)
+	StoreResponse("k", CachedResponse{Status: 200}, time.Minute)
+	if _, ok := LookupResponse("k"); ok {
+		t.Fatal("disabled cache returned a response")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if CacheKey("GET /a", "x", 1) == CacheKey("GET /a", "x1") {
+		t.Error("values are not separated")
+	}
+	if CacheKey("GET /a", "x", 1) != CacheKey("GET /a", "x", 1) {
+		t.Error("keys differ")
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	etag := ETag([]byte("hello"))
+	if etag != ETag([]byte("hello")) || etag == ETag([]byte("hello!")) || etag[0] != '"' {
+		t.Fatalf("ETag %s", etag)
+	}
+	for _, tc := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{etag, true},
+		{`"x", ` + etag, true},
+		{"W/" + etag, true},
+		{"*", true},
+		{`"x"`, false},
+	} {
+		if got := MatchETag(tc.header, etag); got != tc.want {
+			t.Errorf("MatchETag(%q) = %v", tc.header, got)
+		}
+	}
+}
+
+func TestReplyHeader(t *testing.T) {
+	defer SetCache(NewLRUCache(1024))
+	SetCache(NewLRUCache(2))
+
+	// Fiber's response headers point into a buffer the next request reuses.
+	buf := []byte(`</cur?tag=____>; rel="first"`)
+	store := func(key, tag string) {
+		copy(buf[len("</cur?tag="):], tag)
+		h := map[string][]string{
+			"Link":         {unsafe.String(&buf[0], len(buf))},
+			"X-Request-Id": {tag},
+			"Set-Cookie":   {"seen=" + tag, "mw=1"},
+		}
+		StoreResponse(key, CachedResponse{Status: 200, Header: ReplyHeader(h, []string{"link"}, "seen")}, time.Minute)
+	}
+	store("a", "aaaa")
+	store("z", "zzzz")
+
+	r, ok := LookupResponse("a")
+	if !ok {
+		t.Fatal("a missing")
+	}
+	want := map[string][]string{
+		"Link":       {`</cur?tag=aaaa>; rel="first"`},
+		"Set-Cookie": {"seen=aaaa"},
+	}
+	if !reflect.DeepEqual(r.Header, want) {
+		t.Errorf("replayed %v, want %v", r.Header, want)
+	}
+}
@@ -0,0 +1,104 @@
+package goge
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedMediaType is returned by Unmarshal for content types without a codec.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Codec encodes and decodes payloads for one media type.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type codecFuncs struct {
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+}
+
+func (c codecFuncs) Marshal(v any) ([]byte, error)      { return c.marshal(v) }
+func (c codecFuncs) Unmarshal(data []byte, v any) error { return c.unmarshal(data, v) }
+
+var codecs = struct {
+	sync.RWMutex
+	byType map[string]Codec
+}{
+	byType: map[string]Codec{
+		"application/json": codecFuncs{json.Marshal, json.Unmarshal},
+		"application/xml":  codecFuncs{xml.Marshal, xml.Unmarshal},
+	},
+}
+
+// RegisterCodec adds or replaces the codec for a media type. JSON and XML are built in;
+// MessagePack ("application/msgpack") and CBOR ("application/cbor") must be registered
+// by the application with the library of its choice.
+func RegisterCodec(mediaType string, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.byType[mediaType] = c
+}
+
+func lookupCodec(mediaType string) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byType[mediaType]
+}
+
+// RequireCodecs panics unless every media type has a registered codec. Generated
+// RegisterRoutes and GogeRouter call it for the produces= types that are not built in,
+// so that a missing RegisterCodec fails at startup instead of answering 406 to every
+// request.
+func RequireCodecs(mediaTypes ...string) {
+	var missing []string
+	for _, mt := range mediaTypes {
+		if lookupCodec(mt) == nil {
+			missing = append(missing, mt)
+		}
+	}
+	if len(missing) > 0 {
+		panic(fmt.Sprintf("goge: no codec for %s; call goge.RegisterCodec before registering the routes", strings.Join(missing, ", ")))
+	}
+}
+
+// Available filters offers down to the media types that have a registered codec,
+// keeping their order.
+func Available(offers ...string) []string {
+	out := make([]string, 0, len(offers))
+	for _, o := range offers {
+		if lookupCodec(o) != nil {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// Marshal encodes v with the codec registered for mediaType.
+func Marshal(mediaType string, v any) ([]byte, error) {
+	c := lookupCodec(mediaType)
+	if c == nil {
+		return nil, ErrUnsupportedMediaType
+	}
+	return c.Marshal(v)
+}
+
+// Unmarshal decodes data according to contentType, which must be one of allowed.
+func Unmarshal(contentType string, data []byte, v any, allowed ...string) error {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !slices.Contains(allowed, mt) {
+		return ErrUnsupportedMediaType
+	}
+	c := lookupCodec(mt)
+	if c == nil {
+		return ErrUnsupportedMediaType
+	}
+	return c.Unmarshal(data, v)
+}
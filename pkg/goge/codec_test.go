@@ -0,0 +1,49 @@
+package goge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v any) ([]byte, error)      { return []byte("UPPER"), nil }
+func (upperCodec) Unmarshal(data []byte, v any) error { *(v.(*string)) = string(data); return nil }
+
+func TestCodecs(t *testing.T) {
+	if got := Available("application/msgpack", "application/json"); len(got) != 1 || got[0] != "application/json" {
+		t.Fatalf("Available: %v", got)
+	}
+
+	var dst struct{ Name string }
+	if err := Unmarshal("application/json; charset=utf-8", []byte(`{"Name":"x"}`), &dst, "application/json"); err != nil || dst.Name != "x" {
+		t.Fatalf("json decode: %v %+v", err, dst)
+	}
+	if err := Unmarshal("application/xml", []byte(`<x/>`), &dst, "application/json"); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Fatalf("expected unsupported media type, got %v", err)
+	}
+
+	RegisterCodec("application/msgpack", upperCodec{})
+	defer func() {
+		codecs.Lock()
+		delete(codecs.byType, "application/msgpack")
+		codecs.Unlock()
+	}()
+	if b, err := Marshal("application/msgpack", 1); err != nil || string(b) != "UPPER" {
+		t.Fatalf("custom codec: %v %q", err, b)
+	}
+}
+
+func TestRequireCodecs(t *testing.T) {
+	RequireCodecs("application/json", "application/xml")
+
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "application/cbor") || strings.Contains(msg, "application/json") {
+			t.Fatalf("panic: %q", msg)
+		}
+	}()
+	RequireCodecs("application/json", "application/cbor")
+	t.Fatal("no panic for a missing codec")
+}
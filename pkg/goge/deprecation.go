@@ -0,0 +1,62 @@
+package goge
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// Deprecation describes a call to an endpoint annotated deprecated or sunset=, as
+// passed to the hook set with OnDeprecated.
+type Deprecation struct {
+	Method    string // HTTP method of the route
+	Path      string // route as declared, e.g. /v1/users/:id
+	Operation string // name of the service method or function
+	Sunset    string // sunset= date (2006-01-02), or "" when none was announced
+}
+
+var deprecationHook atomic.Pointer[func(context.Context, Deprecation)]
+
+// OnDeprecated sets the function generated handlers call for every request to a
+// deprecated endpoint, after authentication, so that the principal is in ctx. Use it to
+// log or count the callers still to migrate. A nil fn removes the hook.
+func OnDeprecated(fn func(ctx context.Context, d Deprecation)) {
+	if fn == nil {
+		deprecationHook.Store(nil)
+		return
+	}
+	deprecationHook.Store(&fn)
+}
+
+// ReportDeprecated passes d to the hook set with OnDeprecated, if any.
+func ReportDeprecated(ctx context.Context, d Deprecation) {
+	if fn := deprecationHook.Load(); fn != nil {
+		(*fn)(ctx, d)
+	}
+}
+
+// SuccessorPath returns the path of the successor version of a request to a versioned
+// endpoint: /api/v1/users/7 becomes /api/v2/users/7. Requests to the unversioned route,
+// whose pattern route ends with base, get the successor inserted after the prefix the
+// route is mounted under. It returns "" when neither applies.
+func SuccessorPath(path, route, base, version, successor string) string {
+	seg := "/" + version
+	for i := 0; i < len(path); {
+		j := strings.Index(path[i:], seg)
+		if j < 0 {
+			break
+		}
+		end := i + j + len(seg)
+		if end == len(path) || path[end] == '/' {
+			return path[:i+j] + "/" + successor + path[end:]
+		}
+		i = end
+	}
+
+	prefix, ok := strings.CutSuffix(route, base)
+	if !ok || strings.ContainsAny(prefix, ":*+") || !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+	rest := strings.TrimSuffix(path[len(prefix):], "/")
+	return prefix + "/" + successor + rest
+}
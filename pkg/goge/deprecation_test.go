@@ -0,0 +1,43 @@
+package goge
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReportDeprecated(t *testing.T) {
+	ReportDeprecated(context.Background(), Deprecation{Method: "GET"}) // no hook
+
+	var got []Deprecation
+	OnDeprecated(func(ctx context.Context, d Deprecation) { got = append(got, d) })
+	defer OnDeprecated(nil)
+	ReportDeprecated(context.Background(), Deprecation{Method: "GET", Path: "/v1/users", Operation: "ListUsers"})
+	if len(got) != 1 || got[0].Path != "/v1/users" || got[0].Operation != "ListUsers" {
+		t.Fatalf("hook got %+v", got)
+	}
+
+	OnDeprecated(nil)
+	ReportDeprecated(context.Background(), Deprecation{Method: "GET"})
+	if len(got) != 1 {
+		t.Fatalf("removed hook was called: %+v", got)
+	}
+}
+
+func TestSuccessorPath(t *testing.T) {
+	for _, tc := range []struct {
+		path, route, base, want string
+	}{
+		{"/v1/users/7", "/v1/users/:id", "/users/:id", "/v2/users/7"},
+		{"/api/v1/users/7", "/api/v1/users/:id", "/users/:id", "/api/v2/users/7"},
+		{"/v1", "/v1", "/", "/v2"},
+		{"/v1x/v1/users", "/v1x/v1/users", "/users", "/v1x/v2/users"},
+		{"/users/7", "/users/:id", "/users/:id", "/v2/users/7"},
+		{"/api/users/7", "/api/users/:id", "/users/:id", "/api/v2/users/7"},
+		{"/", "/", "/", "/v2"},
+		{"/t/1/users", "/t/:tenant/users", "/users", ""},
+	} {
+		if got := SuccessorPath(tc.path, tc.route, tc.base, "v1", "v2"); got != tc.want {
+			t.Errorf("SuccessorPath(%q, %q, %q) = %q, want %q", tc.path, tc.route, tc.base, got, tc.want)
+		}
+	}
+}
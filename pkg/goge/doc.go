@@ -0,0 +1,3 @@
+// Package goge holds the small runtime pieces imported by code that goge generates.
+// Service packages may use its types (for example EventWriter) in method signatures.
+package goge
@@ -0,0 +1,36 @@
+package goge
+
+import (
+	"io"
+	"mime"
+)
+
+// File is a downloadable result. Services return it (or *File) to stream Reader to the
+// client with a Content-Disposition header.
+type File struct {
+	Name        string // file name offered to the client
+	ContentType string // defaults to the endpoint contentType= or application/octet-stream
+	Size        int64  // length in bytes, 0 when unknown
+	Reader      io.Reader
+	Inline      bool // display in the browser instead of downloading
+}
+
+// Disposition returns the Content-Disposition header value for f.
+func (f File) Disposition() string {
+	kind := "attachment"
+	if f.Inline {
+		kind = "inline"
+	}
+	if f.Name == "" {
+		return kind
+	}
+	return mime.FormatMediaType(kind, map[string]string{"filename": f.Name})
+}
+
+// Length returns Size as the body length for SendStream, -1 when unknown.
+func (f File) Length() int {
+	if f.Size <= 0 {
+		return -1
+	}
+	return int(f.Size)
+}
@@ -0,0 +1,23 @@
+package goge
+
+import "testing"
+
+func TestFileDisposition(t *testing.T) {
+	cases := []struct {
+		f    File
+		want string
+	}{
+		{File{}, "attachment"},
+		{File{Name: "report.csv"}, "attachment; filename=report.csv"},
+		{File{Name: "my report.pdf", Inline: true}, `inline; filename="my report.pdf"`},
+		{File{Name: "résumé.txt"}, "attachment; filename*=utf-8''r%C3%A9sum%C3%A9.txt"},
+	}
+	for _, c := range cases {
+		if got := c.f.Disposition(); got != c.want {
+			t.Errorf("Disposition(%+v) = %q, want %q", c.f, got, c.want)
+		}
+	}
+	if (File{}).Length() != -1 || (File{Size: 10}).Length() != 10 {
+		t.Fatal("Length")
+	}
+}
@@ -0,0 +1,163 @@
+package goge
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page is embedded in the input of a list endpoint served page by page. The generated
+// handler reads ?page= (from 1) and ?limit= into it and answers 400 to a limit above the
+// maximum, set with a tag on the embedded field: `gogePage:"limit=20,max=100"`.
+type Page struct {
+	Number int `json:"-"` // requested page, from 1
+	Limit  int `json:"-"` // items per page
+}
+
+// Offset is the number of items before the page.
+func (p Page) Offset() int { return (p.Number - 1) * p.Limit }
+
+// Bind reads the query values page and limit into p. Generated handlers call it.
+func (p *Page) Bind(page, limit string, defaultLimit, maxLimit int) error {
+	p.Number = 1
+	if page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return fmt.Errorf("page must be a positive integer, got %q", page)
+		}
+		p.Number = n
+	}
+	l, err := parseLimit(limit, defaultLimit, maxLimit)
+	p.Limit = l
+	return err
+}
+
+// Cursor is embedded in the input of a list endpoint served by cursor: the generated
+// handler reads ?cursor= and ?limit= into it, with the limits of Page.
+type Cursor struct {
+	After string `json:"-"` // NextCursor of the previous page, "" for the first page
+	Limit int    `json:"-"` // items per page
+}
+
+// Bind reads the query values cursor and limit into c. Generated handlers call it.
+func (c *Cursor) Bind(cursor, limit string, defaultLimit, maxLimit int) error {
+	c.After = cursor
+	l, err := parseLimit(limit, defaultLimit, maxLimit)
+	c.Limit = l
+	return err
+}
+
+func parseLimit(limit string, defaultLimit, maxLimit int) (int, error) {
+	if limit == "" {
+		return defaultLimit, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer, got %q", limit)
+	}
+	if n > maxLimit {
+		return 0, fmt.Errorf("limit must be at most %d, got %d", maxLimit, n)
+	}
+	return n, nil
+}
+
+// PageResult is one page of a list. Endpoints returning it get Link headers to the
+// other pages (see PageLinks and CursorLinks) and the items, next cursor and total in
+// the body. Set Items to an empty slice rather than nil so an empty page encodes [].
+type PageResult[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // cursor of the next page, "" on the last
+	Total      int    `json:"total,omitempty"`       // items over all pages, when known
+}
+
+// PageLinks returns the Link header of page p of r requested at requestURI: first, prev,
+// next and last pages, keeping the other query values. Without a Total, next is given
+// while pages are full and last is omitted.
+func (r PageResult[T]) PageLinks(requestURI string, p Page) string {
+	u, err := url.Parse(requestURI)
+	if err != nil || p.Limit < 1 {
+		return ""
+	}
+	last := 0
+	if r.Total > 0 {
+		last = (r.Total + p.Limit - 1) / p.Limit
+	}
+	link := func(page int, rel string) string {
+		q := u.Query()
+		q.Set("page", strconv.Itoa(page))
+		q.Set("limit", strconv.Itoa(p.Limit))
+		v := *u
+		v.RawQuery = q.Encode()
+		return "<" + v.String() + `>; rel="` + rel + `"`
+	}
+
+	links := []string{link(1, "first")}
+	if p.Number > 1 {
+		links = append(links, link(p.Number-1, "prev"))
+	}
+	if (last > 0 && p.Number < last) || (last == 0 && len(r.Items) == p.Limit) {
+		links = append(links, link(p.Number+1, "next"))
+	}
+	if last > 0 {
+		links = append(links, link(last, "last"))
+	}
+	return strings.Join(links, ", ")
+}
+
+// CursorLinks returns the Link header of a page of r requested at requestURI with cursor
+// c: the first page, and the next one while r has a NextCursor.
+func (r PageResult[T]) CursorLinks(requestURI string, c Cursor) string {
+	u, err := url.Parse(requestURI)
+	if err != nil {
+		return ""
+	}
+	link := func(cursor, rel string) string {
+		q := u.Query()
+		q.Del("cursor")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		if c.Limit > 0 {
+			q.Set("limit", strconv.Itoa(c.Limit))
+		}
+		v := *u
+		v.RawQuery = q.Encode()
+		return "<" + v.String() + `>; rel="` + rel + `"`
+	}
+
+	links := []string{link("", "first")}
+	if r.NextCursor != "" {
+		links = append(links, link(r.NextCursor, "next"))
+	}
+	return strings.Join(links, ", ")
+}
+
+// ParseLinks maps the relations of Link header values to their URLs, for clients
+// following the pages of a PageResult:
+//
+//	next := goge.ParseLinks(res.Header.Values("Link")...)["next"]
+func ParseLinks(values ...string) map[string]string {
+	out := map[string]string{}
+	for _, v := range values {
+		for _, link := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(val, `"`)) {
+					if _, dup := out[rel]; !dup {
+						out[rel] = target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package goge
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPageBind(t *testing.T) {
+	var p Page
+	if err := p.Bind("", "", 20, 100); err != nil || p.Number != 1 || p.Limit != 20 || p.Offset() != 0 {
+		t.Fatalf("defaults: %+v, %v", p, err)
+	}
+	if err := p.Bind("3", "50", 20, 100); err != nil || p.Number != 3 || p.Limit != 50 || p.Offset() != 100 {
+		t.Fatalf("page 3: %+v, %v", p, err)
+	}
+	for _, bad := range [][2]string{{"0", ""}, {"x", ""}, {"", "0"}, {"", "101"}, {"", "-1"}} {
+		if err := p.Bind(bad[0], bad[1], 20, 100); err == nil {
+			t.Errorf("page=%s limit=%s: expected error", bad[0], bad[1])
+		}
+	}
+
+	var c Cursor
+	if err := c.Bind("abc", "", 10, 50); err != nil || c.After != "abc" || c.Limit != 10 {
+		t.Fatalf("cursor: %+v, %v", c, err)
+	}
+	if err := c.Bind("", "51", 10, 50); err == nil {
+		t.Fatal("expected error for a limit above the maximum")
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	r := PageResult[int]{Items: []int{1, 2}, Total: 5}
+	got := r.PageLinks("/users?status=active&page=2&limit=2", Page{Number: 2, Limit: 2})
+	want := `</users?limit=2&page=1&status=active>; rel="first", </users?limit=2&page=1&status=active>; rel="prev", ` +
+		`</users?limit=2&page=3&status=active>; rel="next", </users?limit=2&page=3&status=active>; rel="last"`
+	if got != want {
+		t.Errorf("PageLinks:\n got %s\nwant %s", got, want)
+	}
+
+	// without a total, a full page has a next one
+	r = PageResult[int]{Items: []int{1, 2}}
+	if links := ParseLinks(r.PageLinks("/users", Page{Number: 1, Limit: 2})); links["next"] != "/users?limit=2&page=2" || links["last"] != "" {
+		t.Errorf("PageLinks without total: %v", links)
+	}
+	r.Items = r.Items[:1]
+	if links := ParseLinks(r.PageLinks("/users", Page{Number: 1, Limit: 2})); links["next"] != "" {
+		t.Errorf("PageLinks of the last page: %v", links)
+	}
+}
+
+func TestCursorLinks(t *testing.T) {
+	r := PageResult[int]{Items: []int{1}, NextCursor: "b2"}
+	links := ParseLinks(r.CursorLinks("/events?cursor=a1&type=x", Cursor{After: "a1", Limit: 10}))
+	if links["first"] != "/events?limit=10&type=x" || links["next"] != "/events?cursor=b2&limit=10&type=x" {
+		t.Errorf("CursorLinks: %v", links)
+	}
+	r.NextCursor = ""
+	if links := ParseLinks(r.CursorLinks("/events", Cursor{})); links["next"] != "" || links["first"] != "/events" {
+		t.Errorf("CursorLinks of the last page: %v", links)
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	links := ParseLinks(`</a?page=2>; rel="next", </a?page=1>; rel="first prev"`, `<https://x.test/docs>; rel=deprecation; type="text/html"`)
+	if links["next"] != "/a?page=2" || links["first"] != "/a?page=1" || links["prev"] != "/a?page=1" || links["deprecation"] != "https://x.test/docs" {
+		t.Errorf("ParseLinks: %v", links)
+	}
+}
+
+func TestPageResultJSON(t *testing.T) {
+	data, err := json.Marshal(PageResult[string]{Items: []string{"a"}, NextCursor: "n", Total: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"items":["a"],"next_cursor":"n","total":3}` {
+		t.Errorf("got %s", data)
+	}
+}
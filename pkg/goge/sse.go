@@ -0,0 +1,163 @@
+package goge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeepAlive is the interval between comment frames sent on idle streams.
+const KeepAlive = 15 * time.Second
+
+// Event is a single Server-Sent Events frame. Values of other types sent on a
+// stream are wrapped as Event{Data: v}.
+type Event struct {
+	ID    string
+	Event string
+	Data  any // strings are written as-is, anything else is JSON encoded
+}
+
+// EventWriter is the sink a service method can take to push events to the client.
+// Send fails once the client has disconnected.
+type EventWriter interface {
+	Send(Event) error
+}
+
+type sseWriter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	seq    int
+	cancel context.CancelFunc // called when a write fails, i.e. the client is gone
+}
+
+func (s *sseWriter) flush() error {
+	err := s.w.Flush()
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+func (s *sseWriter) Send(ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	if ev.ID == "" {
+		ev.ID = fmt.Sprint(s.seq)
+	}
+	data, err := encodeData(ev.Data)
+	if err != nil {
+		return err
+	}
+	if ev.Event != "" {
+		fmt.Fprintf(s.w, "event: %s\n", ev.Event)
+	}
+	fmt.Fprintf(s.w, "id: %s\n", ev.ID)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(s.w, "data: %s\n", line)
+	}
+	s.w.WriteString("\n")
+	return s.flush()
+}
+
+func (s *sseWriter) ping() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.WriteString(": ping\n\n")
+	return s.flush()
+}
+
+func encodeData(v any) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	default:
+		b, err := json.Marshal(d)
+		return string(b), err
+	}
+}
+
+func toEvent(v any) Event {
+	switch ev := v.(type) {
+	case Event:
+		return ev
+	case *Event:
+		if ev != nil {
+			return *ev
+		}
+	}
+	return Event{Data: v}
+}
+
+// StreamSSE writes every value received from ch as an SSE frame until ch is closed,
+// ctx is done or the client goes away. cancel is called on return so producers
+// watching ctx stop as well.
+func StreamSSE[T any](ctx context.Context, cancel context.CancelFunc, w *bufio.Writer, ch <-chan T, keepAlive time.Duration) {
+	defer cancel()
+	sw := &sseWriter{w: w, cancel: cancel}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sw.ping(); err != nil {
+				return
+			}
+		case v, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := sw.Send(toEvent(v)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ServeSSE runs fn with a sink bound to w, sending keep-alive pings while it runs.
+// A disconnect cancels ctx; an error returned by fn is sent as an "error" event.
+func ServeSSE(ctx context.Context, cancel context.CancelFunc, w *bufio.Writer, keepAlive time.Duration, fn func(EventWriter) error) {
+	defer cancel()
+	sw := &sseWriter{w: w, cancel: cancel}
+
+	// the pinger must be gone before w is handed back to the server
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := sw.ping(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(sw)
+	close(done)
+	wg.Wait()
+	if err != nil && ctx.Err() == nil {
+		sw.Send(Event{Event: "error", Data: err.Error()})
+	}
+}
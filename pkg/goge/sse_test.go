@@ -0,0 +1,47 @@
+package goge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamSSE(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	ch := make(chan any, 3)
+	ch <- Event{ID: "a", Event: "progress", Data: map[string]int{"done": 1}}
+	ch <- "line1\nline2"
+	close(ch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	StreamSSE(ctx, cancel, w, (<-chan any)(ch), time.Hour)
+
+	want := "event: progress\nid: a\ndata: {\"done\":1}\n\n" +
+		"id: 2\ndata: line1\ndata: line2\n\n"
+	if buf.String() != want {
+		t.Fatalf("got %q\nwant %q", buf.String(), want)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context must be canceled when the stream ends")
+	}
+}
+
+func TestServeSSE_Error(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	ctx, cancel := context.WithCancel(context.Background())
+	ServeSSE(ctx, cancel, w, time.Hour, func(sink EventWriter) error {
+		if err := sink.Send(Event{Data: "hello"}); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+	if !strings.Contains(buf.String(), "data: hello\n\n") || !strings.Contains(buf.String(), "event: error\nid: 2\ndata: boom\n\n") {
+		t.Fatalf("unexpected stream: %q", buf.String())
+	}
+}
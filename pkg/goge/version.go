@@ -0,0 +1,18 @@
+package goge
+
+import "strings"
+
+// VersionHeader selects the version of an endpoint requested under its unversioned
+// path, e.g. `Accept-Version: v2` for GET /users rather than GET /v2/users.
+const VersionHeader = "Accept-Version"
+
+// AcceptsVersion tells whether version serves a request whose VersionHeader is header.
+// Requests without the header are served by the fallback, the oldest version of the
+// path. "2" and "v2" name the same version.
+func AcceptsVersion(header, version string, fallback bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return fallback
+	}
+	return strings.TrimLeft(strings.ToLower(header), "v") == strings.TrimLeft(strings.ToLower(version), "v")
+}
@@ -0,0 +1,22 @@
+package goge
+
+import "testing"
+
+func TestAcceptsVersion(t *testing.T) {
+	for _, tc := range []struct {
+		header, version string
+		fallback, want  bool
+	}{
+		{"", "v1", true, true},
+		{"", "v2", false, false},
+		{"v2", "v2", false, true},
+		{"2", "v2", false, true},
+		{" V2 ", "v2", true, true},
+		{"v1", "v2", true, false},
+		{"v2.1", "v2", false, false},
+	} {
+		if got := AcceptsVersion(tc.header, tc.version, tc.fallback); got != tc.want {
+			t.Errorf("AcceptsVersion(%q, %q, %v) = %v, want %v", tc.header, tc.version, tc.fallback, got, tc.want)
+		}
+	}
+}
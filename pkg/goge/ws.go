@@ -0,0 +1,147 @@
+package goge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Frame and close codes from RFC 6455, matching the websocket packages used with Fiber.
+const (
+	wsCloseMessage = 8
+	wsPingMessage  = 9
+
+	wsCloseNormal        = 1000
+	wsCloseInvalidData   = 1007
+	wsCloseInternalError = 1011
+)
+
+// WSConn is the subset of *websocket.Conn (github.com/gofiber/contrib/websocket)
+// used by ServeWS.
+type WSConn interface {
+	ReadJSON(v any) error
+	WriteJSON(v any) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadLimit(limit int64)
+	SetReadDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// WSOptions tunes ServeWS.
+type WSOptions struct {
+	ReadLimit    int64         // max bytes per incoming message
+	PingInterval time.Duration // how often the server pings
+	PongWait     time.Duration // how long to wait for a pong before dropping the peer
+}
+
+// DefaultWSOptions are used by generated WebSocket handlers.
+var DefaultWSOptions = WSOptions{
+	ReadLimit:    1 << 20,
+	PingInterval: 30 * time.Second,
+	PongWait:     60 * time.Second,
+}
+
+// ErrInvalidMessage is passed to the close frame when a client message is not valid JSON for In.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// ServeWS decodes client messages as JSON into In, feeds them to fn and encodes every
+// Out that fn sends. It returns once fn returns or the peer goes away; ctx is canceled
+// in both cases so fn can stop.
+func ServeWS[In, Out any](ctx context.Context, conn WSConn, fn func(context.Context, <-chan In, chan<- Out) error, opts WSOptions) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn.SetReadLimit(opts.ReadLimit)
+	conn.SetReadDeadline(time.Now().Add(opts.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(opts.PongWait))
+	})
+
+	in := make(chan In)
+	out := make(chan Out)
+
+	var readErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(in)
+		for {
+			var msg In
+			if err := conn.ReadJSON(&msg); err != nil {
+				// a closed peer only ends the input; fn may still be replying and a
+				// broken connection surfaces on the next write or ping
+				readErr = err
+				if isSyntaxError(err) {
+					cancel()
+				}
+				return
+			}
+			select {
+			case in <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx, in, out) }()
+
+	ticker := time.NewTicker(opts.PingInterval)
+	defer ticker.Stop()
+
+	code, reason := wsCloseNormal, ""
+loop:
+	for {
+		select {
+		case msg := <-out:
+			if err := conn.WriteJSON(msg); err != nil {
+				cancel()
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(wsPingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				cancel()
+			}
+		case err := <-done:
+			if err != nil && ctx.Err() == nil {
+				code, reason = wsCloseInternalError, err.Error()
+			}
+			break loop
+		case <-ctx.Done():
+			// peer is gone or sent garbage; drop pending replies until fn observes ctx
+			for {
+				select {
+				case <-out:
+				case <-done:
+					break loop
+				}
+			}
+		}
+	}
+
+	cancel()
+	conn.SetReadDeadline(time.Now()) // unblock the reader
+	wg.Wait()
+	if isSyntaxError(readErr) {
+		code, reason = wsCloseInvalidData, ErrInvalidMessage.Error()
+	}
+	conn.WriteControl(wsCloseMessage, closePayload(code, reason), time.Now().Add(time.Second))
+	conn.Close()
+}
+
+func isSyntaxError(err error) bool {
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	return errors.As(err, &se) || errors.As(err, &te)
+}
+
+func closePayload(code int, reason string) []byte {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	return append([]byte{byte(code >> 8), byte(code)}, reason...)
+}
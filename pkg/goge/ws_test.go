@@ -0,0 +1,76 @@
+package goge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeConn struct {
+	mu      sync.Mutex
+	inbox   []string
+	written []any
+	closing []byte
+}
+
+func (f *fakeConn) ReadJSON(v any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.inbox) == 0 {
+		return io.EOF
+	}
+	raw := f.inbox[0]
+	f.inbox = f.inbox[1:]
+	return json.Unmarshal([]byte(raw), v)
+}
+
+func (f *fakeConn) WriteJSON(v any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.written = append(f.written, v)
+	return nil
+}
+
+func (f *fakeConn) WriteControl(mt int, data []byte, _ time.Time) error {
+	if mt == wsCloseMessage {
+		f.closing = data
+	}
+	return nil
+}
+
+func (f *fakeConn) SetReadLimit(int64)                {}
+func (f *fakeConn) SetReadDeadline(time.Time) error   { return nil }
+func (f *fakeConn) SetPongHandler(func(string) error) {}
+func (f *fakeConn) Close() error                      { return nil }
+
+func TestServeWS_Echo(t *testing.T) {
+	conn := &fakeConn{inbox: []string{"1", "2"}}
+	ServeWS(context.Background(), conn, func(ctx context.Context, in <-chan int, out chan<- int) error {
+		for v := range in {
+			out <- v * 10
+		}
+		return nil
+	}, DefaultWSOptions)
+
+	if len(conn.written) != 2 || conn.written[0] != 10 || conn.written[1] != 20 {
+		t.Fatalf("unexpected replies: %v", conn.written)
+	}
+	if code := int(conn.closing[0])<<8 | int(conn.closing[1]); code != wsCloseNormal {
+		t.Fatalf("close code %d", code)
+	}
+}
+
+func TestServeWS_InvalidMessage(t *testing.T) {
+	conn := &fakeConn{inbox: []string{`"not a number"`}}
+	ServeWS(context.Background(), conn, func(ctx context.Context, in <-chan int, out chan<- int) error {
+		<-ctx.Done()
+		return nil
+	}, DefaultWSOptions)
+
+	if code := int(conn.closing[0])<<8 | int(conn.closing[1]); code != wsCloseInvalidData {
+		t.Fatalf("close code %d", code)
+	}
+}